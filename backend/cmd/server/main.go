@@ -1,13 +1,20 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"time"
+	_ "time/tzdata" // leaderboard windows use IANA zones; the runtime image has no tzdata
 
 	"github.com/calebwoo/mental-math-trainer/config"
 	"github.com/calebwoo/mental-math-trainer/internal/database"
 	"github.com/calebwoo/mental-math-trainer/internal/handlers"
 	"github.com/calebwoo/mental-math-trainer/internal/middleware"
+	"github.com/calebwoo/mental-math-trainer/internal/repository"
+	"github.com/calebwoo/mental-math-trainer/internal/sweeper"
 	"github.com/gin-gonic/gin"
 )
 
@@ -20,11 +27,42 @@ func main() {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
 
-	// Run migrations
+	// "server migrate <up|down|status>" manages the schema and exits
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrateCommand(os.Args[2:]); err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+		return
+	}
+
+	// Run migrations; the server must not start on a partially migrated schema
 	if err := database.Migrate(); err != nil {
 		log.Fatalf("Failed to run migrations: %v", err)
 	}
 
+	// Build handlers on top of the database repositories. Sessions take
+	// answers for a grace period after their time runs out, and are swept
+	// up in the background if the client abandons them.
+	repos := repository.NewGorm(database.DB)
+	grace := secondsSetting("SESSION_GRACE_SECONDS")
+	h := handlers.New(repos, grace)
+	if interval := secondsSetting("SESSION_SWEEP_INTERVAL_SECONDS"); interval > 0 {
+		discardEmpty, err := strconv.ParseBool(os.Getenv("SESSION_DISCARD_EMPTY"))
+		if err != nil {
+			log.Fatalf("Invalid SESSION_DISCARD_EMPTY: %q", os.Getenv("SESSION_DISCARD_EMPTY"))
+		}
+		abandonAfter := secondsSetting("SESSION_ABANDON_AFTER_SECONDS")
+		if abandonAfter == 0 {
+			log.Fatalf("SESSION_ABANDON_AFTER_SECONDS must be positive")
+		}
+		policy := sweeper.Policy{
+			Grace:        grace,
+			AbandonAfter: abandonAfter,
+			DiscardEmpty: discardEmpty,
+		}
+		go sweeper.New(repos, policy).Run(context.Background(), interval)
+	}
+
 	// Initialize Gin router
 	router := gin.Default()
 
@@ -40,25 +78,30 @@ func main() {
 	api := router.Group("/api")
 	{
 		// Auth routes (no authentication required)
-		api.POST("/auth/register", handlers.Register)
-		api.POST("/auth/login", handlers.Login)
+		api.POST("/auth/register", h.Register)
+		api.POST("/auth/login", h.Login)
 
 		// Leaderboard route (no authentication required)
-		api.GET("/leaderboard", handlers.GetLeaderboard)
+		api.GET("/leaderboard", h.GetLeaderboard)
+		api.GET("/leaderboard/configurations", h.GetPopularConfigurations)
 
 		// Routes with optional authentication
 		optionalAuth := api.Group("/")
 		optionalAuth.Use(middleware.OptionalAuth())
 		{
 			// Session routes (can be used anonymously or authenticated)
-			optionalAuth.POST("/sessions", handlers.CreateSession)
-			optionalAuth.GET("/sessions/:id", handlers.GetSession)
-			optionalAuth.PATCH("/sessions/:id/complete", handlers.CompleteSession)
-			optionalAuth.DELETE("/sessions/:id", handlers.DeleteSession)
-			optionalAuth.GET("/sessions", handlers.GetSessions)
+			optionalAuth.POST("/sessions", h.CreateSession)
+			optionalAuth.GET("/sessions/:id", h.GetSession)
+			optionalAuth.PATCH("/sessions/:id/complete", h.CompleteSession)
+			optionalAuth.DELETE("/sessions/:id", h.DeleteSession)
+			optionalAuth.GET("/sessions", h.GetSessions)
 
 			// Problem routes
-			optionalAuth.POST("/sessions/:id/problems", handlers.SubmitProblem)
+			optionalAuth.POST("/sessions/:id/problems", h.SubmitProblem)
+
+			// Server-issued problem routes
+			optionalAuth.POST("/sessions/:id/next", h.NextProblem)
+			optionalAuth.POST("/sessions/:id/problems/:problemId/answer", h.AnswerProblem)
 		}
 
 		// Protected routes (authentication required)
@@ -66,11 +109,18 @@ func main() {
 		protected.Use(middleware.RequireAuth())
 		{
 			// User profile
-			protected.GET("/auth/me", handlers.GetCurrentUser)
+			protected.GET("/auth/me", h.GetCurrentUser)
+
+			// Leaderboard position
+			protected.GET("/leaderboard/me", h.GetLeaderboardPosition)
 
 			// Settings routes (require authentication)
-			protected.GET("/settings", handlers.GetSettings)
-			protected.PUT("/settings", handlers.UpdateSettings)
+			protected.GET("/settings", h.GetSettings)
+			protected.PUT("/settings", h.UpdateSettings)
+
+			// Analytics
+			protected.GET("/analytics", h.GetAnalytics)
+			protected.GET("/analytics/facts", h.GetFactHeatmap)
 		}
 	}
 
@@ -81,3 +131,49 @@ func main() {
 		log.Fatalf("Failed to start server: %v", err)
 	}
 }
+
+// secondsSetting reads a duration in whole seconds from the environment
+func secondsSetting(key string) time.Duration {
+	seconds, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || seconds < 0 {
+		log.Fatalf("Invalid %s: %q", key, os.Getenv(key))
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// runMigrateCommand handles the migrate subcommand
+func runMigrateCommand(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: migrate <up|down [steps]|status>")
+	}
+
+	switch args[0] {
+	case "up":
+		return database.Migrate()
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				return fmt.Errorf("invalid number of steps: %s", args[1])
+			}
+			steps = n
+		}
+		return database.MigrateDown(steps)
+	case "status":
+		states, err := database.MigrationStatus()
+		if err != nil {
+			return err
+		}
+		for _, state := range states {
+			applied := "pending"
+			if state.AppliedAt != nil {
+				applied = "applied " + state.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%04d_%-30s %s\n", state.Version, state.Name, applied)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown migrate command %q", args[0])
+	}
+}
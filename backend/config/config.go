@@ -21,6 +21,10 @@ func Load() {
 	setDefault("DB_HOST", "localhost")
 	setDefault("DB_PORT", "5432")
 	setDefault("DB_SSLMODE", "disable")
+	setDefault("SESSION_GRACE_SECONDS", "10")
+	setDefault("SESSION_SWEEP_INTERVAL_SECONDS", "60")
+	setDefault("SESSION_ABANDON_AFTER_SECONDS", "1800")
+	setDefault("SESSION_DISCARD_EMPTY", "true")
 
 	// Check for required JWT_SECRET in production
 	if os.Getenv("JWT_SECRET") == "" {
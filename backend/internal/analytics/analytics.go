@@ -0,0 +1,165 @@
+package analytics
+
+import (
+	"math"
+	"sort"
+
+	"github.com/calebwoo/mental-math-trainer/internal/generator"
+	"github.com/calebwoo/mental-math-trainer/internal/models"
+)
+
+// Unknown groups problems whose operation isn't known
+const Unknown = "unknown"
+
+// operationOrder is the order operations are reported in
+var operationOrder = []string{
+	string(generator.Addition),
+	string(generator.Subtraction),
+	string(generator.Multiplication),
+	string(generator.Division),
+	Unknown,
+}
+
+// ByOperation summarizes answered problems per operation, along with the
+// summary across all of them. Operations with no problems are left out.
+func ByOperation(problems []models.Problem) models.AnalyticsResponse {
+	groups := make(map[string][]models.Problem)
+	for _, problem := range problems {
+		op := problem.Operation
+		if op == "" {
+			op = Unknown
+		}
+		groups[op] = append(groups[op], problem)
+	}
+
+	response := models.AnalyticsResponse{
+		Overall:    summarize("all", problems),
+		Operations: []models.OperationStats{},
+	}
+	for _, op := range operationOrder {
+		if len(groups[op]) > 0 {
+			response.Operations = append(response.Operations, summarize(op, groups[op]))
+		}
+	}
+	return response
+}
+
+// summarize computes the statistics of one group of problems
+func summarize(operation string, problems []models.Problem) models.OperationStats {
+	stats := models.OperationStats{Operation: operation, Count: len(problems)}
+	if len(problems) == 0 {
+		return stats
+	}
+
+	times := make([]int, len(problems))
+	total, withTypos := 0, 0
+	for i, problem := range problems {
+		if problem.IsCorrect {
+			stats.Correct++
+		}
+		if problem.TypoCount > 0 {
+			withTypos++
+		}
+		times[i] = problem.TimeSpentMs
+		total += problem.TimeSpentMs
+	}
+	sort.Ints(times)
+
+	n := float64(len(problems))
+	stats.Accuracy = round(float64(stats.Correct) / n)
+	stats.TypoRate = round(float64(withTypos) / n)
+	stats.MeanTimeMs = round(float64(total) / n)
+	stats.MedianTimeMs = median(times)
+	stats.P90TimeMs = percentile(times, 90)
+	return stats
+}
+
+// median returns the middle of sorted values, averaging the two middle
+// values of an even-sized set
+func median(sorted []int) float64 {
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 1 {
+		return float64(sorted[mid])
+	}
+	return float64(sorted[mid-1]+sorted[mid]) / 2
+}
+
+// percentile returns the nearest-rank percentile of sorted values
+func percentile(sorted []int, p int) int {
+	rank := int(math.Ceil(float64(p) / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// round keeps four decimal places, enough for rates and millisecond means
+func round(x float64) float64 {
+	return math.Round(x*10000) / 10000
+}
+
+// Heatmap arranges answered problems of one operation into a grid of facts.
+// Problems of other operations, or without operands, are ignored.
+func Heatmap(op generator.Operation, problems []models.Problem) models.FactHeatmap {
+	type tally struct {
+		attempts, correct, totalTime int
+	}
+	facts := make(map[generator.Fact]*tally)
+	rowSet := make(map[int]bool)
+	columnSet := make(map[int]bool)
+
+	for _, problem := range problems {
+		if problem.Operation != string(op) {
+			continue
+		}
+		fact, ok := generator.FactOf(op, problem.Operands, problem.Answer)
+		if !ok {
+			continue
+		}
+
+		t, ok := facts[fact]
+		if !ok {
+			t = &tally{}
+			facts[fact] = t
+			rowSet[fact.A] = true
+			columnSet[fact.B] = true
+		}
+		t.attempts++
+		t.totalTime += problem.TimeSpentMs
+		if problem.IsCorrect {
+			t.correct++
+		}
+	}
+
+	heatmap := models.FactHeatmap{
+		Operation: string(op),
+		Rows:      sortedKeys(rowSet),
+		Columns:   sortedKeys(columnSet),
+	}
+	heatmap.Cells = make([][]*models.FactCell, len(heatmap.Rows))
+	for i, a := range heatmap.Rows {
+		heatmap.Cells[i] = make([]*models.FactCell, len(heatmap.Columns))
+		for j, b := range heatmap.Columns {
+			t, ok := facts[generator.Fact{Operation: op, A: a, B: b}]
+			if !ok {
+				continue
+			}
+			heatmap.Cells[i][j] = &models.FactCell{
+				Attempts:   t.attempts,
+				Correct:    t.correct,
+				Accuracy:   round(float64(t.correct) / float64(t.attempts)),
+				MeanTimeMs: round(float64(t.totalTime) / float64(t.attempts)),
+			}
+		}
+	}
+	return heatmap
+}
+
+func sortedKeys(set map[int]bool) []int {
+	keys := make([]int, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Ints(keys)
+	return keys
+}
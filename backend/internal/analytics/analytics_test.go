@@ -0,0 +1,97 @@
+package analytics
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/calebwoo/mental-math-trainer/internal/generator"
+	"github.com/calebwoo/mental-math-trainer/internal/models"
+)
+
+func TestByOperation(t *testing.T) {
+	problems := []models.Problem{
+		{Operation: "multiplication", IsCorrect: true, TimeSpentMs: 1000},
+		{Operation: "multiplication", IsCorrect: false, TimeSpentMs: 3000, TypoCount: 1},
+		{Operation: "multiplication", IsCorrect: true, TimeSpentMs: 2000},
+		{Operation: "addition", IsCorrect: true, TimeSpentMs: 1500},
+		{IsCorrect: false, TimeSpentMs: 4000}, // recorded before problems had an operation
+	}
+
+	want := models.AnalyticsResponse{
+		Overall: models.OperationStats{
+			Operation: "all", Count: 5, Correct: 3, Accuracy: 0.6,
+			MeanTimeMs: 2300, MedianTimeMs: 2000, P90TimeMs: 4000, TypoRate: 0.2,
+		},
+		Operations: []models.OperationStats{
+			{
+				Operation: "addition", Count: 1, Correct: 1, Accuracy: 1,
+				MeanTimeMs: 1500, MedianTimeMs: 1500, P90TimeMs: 1500,
+			},
+			{
+				Operation: "multiplication", Count: 3, Correct: 2, Accuracy: 0.6667,
+				MeanTimeMs: 2000, MedianTimeMs: 2000, P90TimeMs: 3000, TypoRate: 0.3333,
+			},
+			{
+				Operation: Unknown, Count: 1,
+				MeanTimeMs: 4000, MedianTimeMs: 4000, P90TimeMs: 4000,
+			},
+		},
+	}
+	if got := ByOperation(problems); !reflect.DeepEqual(got, want) {
+		t.Errorf("ByOperation = %+v, want %+v", got, want)
+	}
+}
+
+func TestByOperationWithNoProblems(t *testing.T) {
+	got := ByOperation(nil)
+	if got.Overall.Count != 0 || got.Operations == nil || len(got.Operations) != 0 {
+		t.Errorf("ByOperation(nil) = %+v, want an empty summary and no operations", got)
+	}
+}
+
+func TestMedianAndPercentile(t *testing.T) {
+	if got := median([]int{1, 2, 3, 10}); got != 2.5 {
+		t.Errorf("median of an even set = %v, want 2.5", got)
+	}
+	sorted := []int{10, 20, 30, 40, 50, 60, 70, 80, 90, 100}
+	for p, want := range map[int]int{90: 90, 50: 50, 100: 100, 1: 10} {
+		if got := percentile(sorted, p); got != want {
+			t.Errorf("percentile %d = %d, want %d", p, got, want)
+		}
+	}
+}
+
+func TestHeatmap(t *testing.T) {
+	problems := []models.Problem{
+		{Operation: "multiplication", Operands: models.Operands{7, 8}, Answer: 56, IsCorrect: true, TimeSpentMs: 1000},
+		{Operation: "multiplication", Operands: models.Operands{8, 7}, Answer: 56, IsCorrect: false, TimeSpentMs: 3000},
+		{Operation: "multiplication", Operands: models.Operands{3, 8}, Answer: 24, IsCorrect: true, TimeSpentMs: 2000},
+		{Operation: "multiplication", Operands: models.Operands{4, 3}, Answer: 12, IsCorrect: true, TimeSpentMs: 500},
+		{Operation: "division", Operands: models.Operands{56, 7}, Answer: 8, IsCorrect: true, TimeSpentMs: 900},
+		{Operation: "multiplication", Answer: 20, IsCorrect: true, TimeSpentMs: 700}, // no operands
+	}
+
+	want := models.FactHeatmap{
+		Operation: "multiplication",
+		Rows:      []int{3, 7},
+		Columns:   []int{4, 8},
+		Cells: [][]*models.FactCell{
+			{
+				{Attempts: 1, Correct: 1, Accuracy: 1, MeanTimeMs: 500},
+				{Attempts: 1, Correct: 1, Accuracy: 1, MeanTimeMs: 2000},
+			},
+			{
+				nil,
+				{Attempts: 2, Correct: 1, Accuracy: 0.5, MeanTimeMs: 2000},
+			},
+		},
+	}
+	if got := Heatmap(generator.Multiplication, problems); !reflect.DeepEqual(got, want) {
+		t.Errorf("Heatmap = %+v, want %+v", got, want)
+	}
+
+	division := Heatmap(generator.Division, problems)
+	if !reflect.DeepEqual(division.Rows, []int{7}) || !reflect.DeepEqual(division.Columns, []int{8}) {
+		t.Errorf("division heatmap has rows %v and columns %v, want [7] and [8]", division.Rows, division.Columns)
+	}
+}
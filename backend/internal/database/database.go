@@ -4,8 +4,9 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
 
-	"github.com/calebwoo/mental-math-trainer/internal/models"
+	"github.com/glebarez/sqlite"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
@@ -13,10 +14,75 @@ import (
 
 var DB *gorm.DB
 
-// Connect initializes the database connection
+// Connect initializes the database connection. SQLite is used when
+// DB_DRIVER is "sqlite" or DATABASE_URL has a sqlite:// or file: scheme;
+// otherwise Postgres is used.
 func Connect() error {
 	var err error
 
+	var dialector gorm.Dialector
+	if path, ok := sqlitePath(); ok {
+		dialector = sqlite.Open(sqliteDSN(path))
+		log.Printf("Using SQLite database at %s", path)
+	} else {
+		dialector = postgresDialector()
+	}
+
+	DB, err = gorm.Open(dialector, &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Info),
+	})
+
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+
+	// SQLite allows a single writer, so serialize access through one connection
+	if DB.Dialector.Name() == "sqlite" {
+		sqlDB, err := DB.DB()
+		if err != nil {
+			return fmt.Errorf("failed to configure database: %w", err)
+		}
+		sqlDB.SetMaxOpenConns(1)
+	}
+
+	log.Println("Database connection established")
+	return nil
+}
+
+// sqlitePath returns the SQLite database file to use, if SQLite is selected
+func sqlitePath() (string, bool) {
+	dsn := os.Getenv("DATABASE_URL")
+	for _, scheme := range []string{"sqlite://", "sqlite:", "file:"} {
+		if strings.HasPrefix(dsn, scheme) {
+			return strings.TrimPrefix(dsn, scheme), true
+		}
+	}
+
+	if os.Getenv("DB_DRIVER") == "sqlite" {
+		path := os.Getenv("DB_PATH")
+		if path == "" {
+			path = "mental_math_trainer.db"
+		}
+		return path, true
+	}
+
+	return "", false
+}
+
+// sqlitePragmas turn on foreign keys and wait for locks instead of failing
+const sqlitePragmas = "_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)"
+
+// sqliteDSN adds the connection pragmas to a SQLite path, which may already
+// have a query string such as "app.db?cache=shared"
+func sqliteDSN(path string) string {
+	if strings.Contains(path, "?") {
+		return path + "&" + sqlitePragmas
+	}
+	return path + "?" + sqlitePragmas
+}
+
+// postgresDialector builds the Postgres dialector from DATABASE_URL or DB_* variables
+func postgresDialector() gorm.Dialector {
 	// Check for DATABASE_URL first (used by many cloud providers)
 	dsn := os.Getenv("DATABASE_URL")
 
@@ -47,41 +113,8 @@ func Connect() error {
 	}
 
 	// Add pgx config for Supabase pooler compatibility
-	config := postgres.Config{
+	return postgres.New(postgres.Config{
 		DSN:                  dsn,
 		PreferSimpleProtocol: true, // Disable prepared statements for pooler
-	}
-
-	DB, err = gorm.Open(postgres.New(config), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Info),
 	})
-
-	if err != nil {
-		return fmt.Errorf("failed to connect to database: %w", err)
-	}
-
-	log.Println("Database connection established")
-	return nil
-}
-
-// Migrate runs database migrations
-func Migrate() error {
-	log.Println("Running database migrations...")
-
-	// AutoMigrate will create tables, missing columns, and missing indexes
-	// It WON'T delete unused columns to protect your data
-	err := DB.AutoMigrate(
-		&models.User{},
-		&models.Session{},
-		&models.Problem{},
-		&models.Settings{},
-	)
-
-	if err != nil {
-		// Log the error but don't fail - tables likely already exist
-		log.Printf("Migration notice: %v (tables may already exist, continuing...)", err)
-	}
-
-	log.Println("Database migrations completed")
-	return nil
 }
@@ -0,0 +1,491 @@
+package database
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// migrations lists every schema change in order. Applied migrations must
+// never be edited; add a new one instead. Each migration works on its own
+// snapshot of the tables it touches, so later model changes don't alter
+// what an old migration does.
+var migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "initial_schema",
+		Up: func(tx *gorm.DB) error {
+			// Matches the schema previously created by AutoMigrate, so this
+			// is a no-op on databases that already have the tables
+			return tx.AutoMigrate(&userV1{}, &sessionV1{}, &problemV1{}, &settingsV1{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&problemV1{}, &settingsV1{}, &sessionV1{}, &userV1{})
+		},
+	},
+	{
+		Version: 2,
+		Name:    "session_integrity",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&sessionV2{})
+		},
+		Down: func(tx *gorm.DB) error {
+			m := tx.Migrator()
+			if m.HasIndex(&sessionV2{}, "AnonymousID") {
+				if err := m.DropIndex(&sessionV2{}, "AnonymousID"); err != nil {
+					return err
+				}
+			}
+			return dropColumns(tx, "sessions", "server_issued", "leaderboard_eligible", "flag_reason", "secret_hash", "anonymous_id")
+		},
+	},
+	{
+		Version: 3,
+		Name:    "session_settings_fingerprint",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.AutoMigrate(&sessionV3{}); err != nil {
+				return err
+			}
+			// Sessions already marked as default settings were played with
+			// the defaults, so they keep their place on the default leaderboard
+			return tx.Table("sessions").
+				Where("is_default_settings = ?", true).
+				Update("settings_fingerprint", defaultSettingsFingerprintV3).Error
+		},
+		Down: func(tx *gorm.DB) error {
+			m := tx.Migrator()
+			if m.HasIndex(&sessionV3{}, "SettingsFingerprint") {
+				if err := m.DropIndex(&sessionV3{}, "SettingsFingerprint"); err != nil {
+					return err
+				}
+			}
+			return dropColumns(tx, "sessions", "settings_fingerprint")
+		},
+	},
+	{
+		Version: 4,
+		Name:    "session_settings",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.AutoMigrate(&sessionSettingsV4{}); err != nil {
+				return err
+			}
+
+			// Sessions marked as default settings were played with the
+			// defaults, so give them a snapshot of those
+			var sessionIDs []uint
+			err := tx.Table("sessions").
+				Where("is_default_settings = ?", true).
+				Pluck("id", &sessionIDs).Error
+			if err != nil || len(sessionIDs) == 0 {
+				return err
+			}
+
+			snapshots := make([]sessionSettingsV4, len(sessionIDs))
+			for i, id := range sessionIDs {
+				snapshots[i] = defaultSessionSettingsV4
+				snapshots[i].SessionID = id
+				snapshots[i].CreatedAt = time.Now()
+			}
+			return tx.CreateInBatches(snapshots, 100).Error
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&sessionSettingsV4{})
+		},
+	},
+	{
+		Version: 5,
+		Name:    "problem_operands",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.AutoMigrate(&problemV5{}); err != nil {
+				return err
+			}
+
+			// Read the operation and operands of existing problems from
+			// their question strings
+			var problems []problemV5
+			return tx.Select("id", "question").
+				FindInBatches(&problems, 500, func(*gorm.DB, int) error {
+					for _, problem := range problems {
+						op, operands, ok := parseQuestionV5(problem.Question)
+						if !ok {
+							continue
+						}
+						err := tx.Model(&problemV5{ID: problem.ID}).Updates(map[string]interface{}{
+							"operation":  op,
+							"operands":   strings.Join(operands, ","),
+							"difficulty": difficultyV5(operands),
+						}).Error
+						if err != nil {
+							return err
+						}
+					}
+					return nil
+				}).Error
+		},
+		Down: func(tx *gorm.DB) error {
+			m := tx.Migrator()
+			if m.HasIndex(&problemV5{}, "Operation") {
+				if err := m.DropIndex(&problemV5{}, "Operation"); err != nil {
+					return err
+				}
+			}
+			return dropColumns(tx, "problems", "operation", "operands", "difficulty")
+		},
+	},
+	{
+		Version: 6,
+		Name:    "weak_facts_practice",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&sessionV6{}, &factScheduleV6{})
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropTable(&factScheduleV6{}); err != nil {
+				return err
+			}
+			return dropColumns(tx, "sessions", "mode")
+		},
+	},
+	{
+		Version: 7,
+		Name:    "sprint_mode",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&sessionV7{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return dropColumns(tx, "sessions", "target_count")
+		},
+	},
+	{
+		Version: 8,
+		Name:    "wrong_answer_modes",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&sessionV8{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return dropColumns(tx, "sessions", "wrong_answers", "penalty_seconds")
+		},
+	},
+	{
+		Version: 9,
+		Name:    "session_abandonment",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&sessionV9{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return dropColumns(tx, "sessions", "abandon_reason")
+		},
+	},
+}
+
+// dropColumns drops the given columns of a table that still exist. It uses
+// ALTER TABLE directly because GORM's SQLite migrator rebuilds the table,
+// which loses its indexes and fails while other tables reference it.
+func dropColumns(tx *gorm.DB, table string, columns ...string) error {
+	for _, column := range columns {
+		if !tx.Migrator().HasColumn(table, column) {
+			continue
+		}
+		err := tx.Exec("ALTER TABLE ? DROP COLUMN ?", clause.Table{Name: table}, clause.Column{Name: column}).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Version 1 snapshots: the original schema
+
+type userV1 struct {
+	ID           uint   `gorm:"primaryKey"`
+	Username     string `gorm:"uniqueIndex;not null"`
+	Email        string `gorm:"uniqueIndex;not null"`
+	PasswordHash string
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	DeletedAt    gorm.DeletedAt `gorm:"index"`
+	Sessions     []sessionV1    `gorm:"foreignKey:UserID"`
+	Settings     *settingsV1    `gorm:"foreignKey:UserID"`
+}
+
+func (userV1) TableName() string { return "users" }
+
+type sessionV1 struct {
+	ID                uint `gorm:"primaryKey"`
+	UserID            *uint
+	User              *userV1 `gorm:"foreignKey:UserID"`
+	AnonymousName     string
+	Score             int
+	Duration          int
+	IsDefaultSettings bool `gorm:"default:false"`
+	StartedAt         time.Time
+	EndedAt           *time.Time
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+	DeletedAt         gorm.DeletedAt `gorm:"index"`
+	Problems          []problemV1    `gorm:"foreignKey:SessionID"`
+}
+
+func (sessionV1) TableName() string { return "sessions" }
+
+type problemV1 struct {
+	ID          uint `gorm:"primaryKey"`
+	SessionID   uint
+	Question    string
+	Answer      int
+	UserAnswer  *int
+	TimeSpentMs int
+	TypoCount   int
+	IsCorrect   bool
+	CreatedAt   time.Time
+}
+
+func (problemV1) TableName() string { return "problems" }
+
+type settingsV1 struct {
+	ID        uint `gorm:"primaryKey"`
+	UserID    uint `gorm:"uniqueIndex"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+
+	AdditionEnabled bool `gorm:"default:true"`
+	AdditionMin1    int  `gorm:"default:2;column:addition_min1"`
+	AdditionMax1    int  `gorm:"default:100;column:addition_max1"`
+	AdditionMin2    int  `gorm:"default:2;column:addition_min2"`
+	AdditionMax2    int  `gorm:"default:100;column:addition_max2"`
+
+	SubtractionEnabled bool `gorm:"default:true"`
+	SubtractionMin1    int  `gorm:"default:2;column:subtraction_min1"`
+	SubtractionMax1    int  `gorm:"default:100;column:subtraction_max1"`
+	SubtractionMin2    int  `gorm:"default:2;column:subtraction_min2"`
+	SubtractionMax2    int  `gorm:"default:100;column:subtraction_max2"`
+
+	MultiplicationEnabled bool `gorm:"default:true"`
+	MultiplicationMin1    int  `gorm:"default:2;column:multiplication_min1"`
+	MultiplicationMax1    int  `gorm:"default:12;column:multiplication_max1"`
+	MultiplicationMin2    int  `gorm:"default:2;column:multiplication_min2"`
+	MultiplicationMax2    int  `gorm:"default:100;column:multiplication_max2"`
+
+	DivisionEnabled bool `gorm:"default:false"`
+	DivisionMin1    int  `gorm:"default:2;column:division_min1"`
+	DivisionMax1    int  `gorm:"default:12;column:division_max1"`
+	DivisionMin2    int  `gorm:"default:2;column:division_min2"`
+	DivisionMax2    int  `gorm:"default:100;column:division_max2"`
+}
+
+func (settingsV1) TableName() string { return "settings" }
+
+// Version 2 snapshot: server-issued problems, score checks and anonymous ownership
+
+type sessionV2 struct {
+	ID                  uint `gorm:"primaryKey"`
+	UserID              *uint
+	AnonymousName       string
+	AnonymousID         string `gorm:"index"`
+	SecretHash          string
+	Score               int
+	Duration            int
+	IsDefaultSettings   bool `gorm:"default:false"`
+	ServerIssued        bool `gorm:"default:false"`
+	LeaderboardEligible bool `gorm:"default:true"`
+	FlagReason          string
+	StartedAt           time.Time
+	EndedAt             *time.Time
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+	DeletedAt           gorm.DeletedAt `gorm:"index"`
+}
+
+func (sessionV2) TableName() string { return "sessions" }
+
+// Version 3 snapshot: the settings fingerprint column added to sessions
+
+type sessionV3 struct {
+	ID                  uint   `gorm:"primaryKey"`
+	SettingsFingerprint string `gorm:"index"`
+}
+
+func (sessionV3) TableName() string { return "sessions" }
+
+// defaultSettingsFingerprintV3 is the fingerprint of the default settings
+// when version 3 was written
+const defaultSettingsFingerprintV3 = "add:2-100:2-100,sub:2-100:2-100,mul:2-12:2-100,div:2-12:2-100"
+
+// Version 4 snapshot: settings copied onto each session
+
+type sessionSettingsV4 struct {
+	ID        uint `gorm:"primaryKey"`
+	SessionID uint `gorm:"uniqueIndex"`
+	CreatedAt time.Time
+
+	Operations operationSettingsV4 `gorm:"embedded"`
+}
+
+func (sessionSettingsV4) TableName() string { return "session_settings" }
+
+// operationSettingsV4 are the operation columns of settings when version 4
+// was written
+type operationSettingsV4 struct {
+	AdditionEnabled bool
+	AdditionMin1    int `gorm:"column:addition_min1"`
+	AdditionMax1    int `gorm:"column:addition_max1"`
+	AdditionMin2    int `gorm:"column:addition_min2"`
+	AdditionMax2    int `gorm:"column:addition_max2"`
+
+	SubtractionEnabled bool
+	SubtractionMin1    int `gorm:"column:subtraction_min1"`
+	SubtractionMax1    int `gorm:"column:subtraction_max1"`
+	SubtractionMin2    int `gorm:"column:subtraction_min2"`
+	SubtractionMax2    int `gorm:"column:subtraction_max2"`
+
+	MultiplicationEnabled bool
+	MultiplicationMin1    int `gorm:"column:multiplication_min1"`
+	MultiplicationMax1    int `gorm:"column:multiplication_max1"`
+	MultiplicationMin2    int `gorm:"column:multiplication_min2"`
+	MultiplicationMax2    int `gorm:"column:multiplication_max2"`
+
+	DivisionEnabled bool
+	DivisionMin1    int `gorm:"column:division_min1"`
+	DivisionMax1    int `gorm:"column:division_max1"`
+	DivisionMin2    int `gorm:"column:division_min2"`
+	DivisionMax2    int `gorm:"column:division_max2"`
+}
+
+// defaultSessionSettingsV4 are the default settings when version 4 was written
+var defaultSessionSettingsV4 = sessionSettingsV4{Operations: operationSettingsV4{
+	AdditionEnabled:       true,
+	AdditionMin1:          2,
+	AdditionMax1:          100,
+	AdditionMin2:          2,
+	AdditionMax2:          100,
+	SubtractionEnabled:    true,
+	SubtractionMin1:       2,
+	SubtractionMax1:       100,
+	SubtractionMin2:       2,
+	SubtractionMax2:       100,
+	MultiplicationEnabled: true,
+	MultiplicationMin1:    2,
+	MultiplicationMax1:    12,
+	MultiplicationMin2:    2,
+	MultiplicationMax2:    100,
+	DivisionEnabled:       true,
+	DivisionMin1:          2,
+	DivisionMax1:          12,
+	DivisionMin2:          2,
+	DivisionMax2:          100,
+}}
+
+// Version 5 snapshot: structured operands added to problems
+
+type problemV5 struct {
+	ID         uint `gorm:"primaryKey"`
+	Question   string
+	Operation  string `gorm:"index"`
+	Operands   string
+	Difficulty string
+}
+
+func (problemV5) TableName() string { return "problems" }
+
+// parseQuestionV5 reads questions as the generator wrote them when version 5
+// was written, returning the operation name and the operands as text
+func parseQuestionV5(question string) (string, []string, bool) {
+	symbols := map[string]string{
+		"+": "addition",
+		"-": "subtraction",
+		"−": "subtraction",
+		"×": "multiplication",
+		"*": "multiplication",
+		"x": "multiplication",
+		"÷": "division",
+		"/": "division",
+	}
+
+	fields := strings.Fields(question)
+	if len(fields) != 3 {
+		return "", nil, false
+	}
+	op, ok := symbols[fields[1]]
+	if !ok {
+		return "", nil, false
+	}
+
+	operands := []string{fields[0], fields[2]}
+	for i, operand := range operands {
+		n, err := strconv.Atoi(operand)
+		if err != nil {
+			return "", nil, false
+		}
+		operands[i] = strconv.Itoa(n)
+	}
+	return op, operands, true
+}
+
+// difficultyV5 counts the digits in each operand, such as "3x2"
+func difficultyV5(operands []string) string {
+	digits := make([]string, len(operands))
+	for i, operand := range operands {
+		digits[i] = strconv.Itoa(len(strings.TrimPrefix(operand, "-")))
+	}
+	return strings.Join(digits, "x")
+}
+
+// Version 6 snapshots: session modes and spaced-repetition schedules
+
+type sessionV6 struct {
+	ID   uint   `gorm:"primaryKey"`
+	Mode string `gorm:"default:standard"`
+}
+
+func (sessionV6) TableName() string { return "sessions" }
+
+type factScheduleV6 struct {
+	ID              uint   `gorm:"primaryKey"`
+	UserID          uint   `gorm:"uniqueIndex:idx_fact_schedules_fact"`
+	Operation       string `gorm:"uniqueIndex:idx_fact_schedules_fact"`
+	A               int    `gorm:"uniqueIndex:idx_fact_schedules_fact"`
+	B               int    `gorm:"uniqueIndex:idx_fact_schedules_fact"`
+	Attempts        int
+	Correct         int
+	TotalTimeMs     int
+	Streak          int
+	Ease            float64
+	IntervalSeconds int
+	DueAt           time.Time
+	LastSeenAt      time.Time
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+}
+
+func (factScheduleV6) TableName() string { return "fact_schedules" }
+
+// Version 7 snapshot: sprint target counts
+
+type sessionV7 struct {
+	ID          uint `gorm:"primaryKey"`
+	TargetCount int
+}
+
+func (sessionV7) TableName() string { return "sessions" }
+
+// Version 8 snapshot: wrong answers counted by sudden death and penalty modes
+
+type sessionV8 struct {
+	ID             uint `gorm:"primaryKey"`
+	WrongAnswers   int
+	PenaltySeconds int
+}
+
+func (sessionV8) TableName() string { return "sessions" }
+
+// Version 9 snapshot: why abandoned sessions were ended
+
+type sessionV9 struct {
+	ID            uint `gorm:"primaryKey"`
+	AbandonReason string
+}
+
+func (sessionV9) TableName() string { return "sessions" }
@@ -0,0 +1,75 @@
+package generator
+
+import "github.com/calebwoo/mental-math-trainer/internal/models"
+
+// Answer returns the answer to a problem with the given operands in
+// question order. It returns false if there isn't a whole-number answer.
+func Answer(op Operation, operands []int) (int, bool) {
+	if len(operands) != 2 {
+		return 0, false
+	}
+	left, right := operands[0], operands[1]
+
+	switch op {
+	case Addition:
+		return left + right, true
+	case Subtraction:
+		return left - right, true
+	case Multiplication:
+		return left * right, true
+	case Division:
+		if right == 0 || left%right != 0 {
+			return 0, false
+		}
+		return left / right, true
+	default:
+		return 0, false
+	}
+}
+
+// Fits reports whether the settings could have generated a problem. The
+// operation must be enabled, and the numbers the generator picks must fall
+// in their ranges: both operands for addition, the subtrahend and answer
+// for subtraction, the factors in either order for multiplication, and the
+// divisor and quotient for division.
+func Fits(settings models.Settings, op Operation, operands []int) bool {
+	answer, ok := Answer(op, operands)
+	if !ok {
+		return false
+	}
+
+	enabled := false
+	for _, e := range EnabledOperations(settings) {
+		enabled = enabled || e == op
+	}
+	if !enabled {
+		return false
+	}
+
+	r := OperandRanges(settings, op)
+	first := func(n int) bool { return n >= r[0] && n <= r[1] }
+	second := func(n int) bool { return n >= r[2] && n <= r[3] }
+	either := func(a, b int) bool { return first(a) && second(b) || first(b) && second(a) }
+
+	switch op {
+	case Addition:
+		return first(operands[0]) && second(operands[1])
+	case Subtraction:
+		return either(operands[1], answer)
+	case Multiplication:
+		return either(operands[0], operands[1])
+	default:
+		return first(operands[1]) && second(answer)
+	}
+}
+
+// FactFits reports whether the settings could have generated a problem
+// drilling the fact, in either order
+func FactFits(settings models.Settings, fact Fact) bool {
+	for _, p := range []Problem{factProblem(fact.Operation, fact.A, fact.B), factProblem(fact.Operation, fact.B, fact.A)} {
+		if Fits(settings, p.Operation, p.Operands) {
+			return true
+		}
+	}
+	return false
+}
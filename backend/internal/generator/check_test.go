@@ -0,0 +1,115 @@
+package generator
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/calebwoo/mental-math-trainer/internal/models"
+)
+
+func TestAnswer(t *testing.T) {
+	tests := []struct {
+		op       Operation
+		operands []int
+		answer   int
+		ok       bool
+	}{
+		{Addition, []int{12, 7}, 19, true},
+		{Subtraction, []int{20, 8}, 12, true},
+		{Multiplication, []int{6, 7}, 42, true},
+		{Division, []int{144, 12}, 12, true},
+		{Division, []int{10, 3}, 0, false},
+		{Division, []int{10, 0}, 0, false},
+		{Addition, []int{1}, 0, false},
+		{"modulo", []int{10, 3}, 0, false},
+	}
+
+	for _, tt := range tests {
+		answer, ok := Answer(tt.op, tt.operands)
+		if answer != tt.answer || ok != tt.ok {
+			t.Errorf("Answer(%s, %v) = %d, %v; want %d, %v", tt.op, tt.operands, answer, ok, tt.answer, tt.ok)
+		}
+	}
+}
+
+func TestFits(t *testing.T) {
+	settings := models.Settings{OperationSettings: models.OperationSettings{
+		AdditionEnabled:       true,
+		AdditionMin1:          1,
+		AdditionMax1:          9,
+		AdditionMin2:          10,
+		AdditionMax2:          99,
+		SubtractionEnabled:    true,
+		SubtractionMin1:       1,
+		SubtractionMax1:       9,
+		SubtractionMin2:       10,
+		SubtractionMax2:       99,
+		MultiplicationEnabled: true,
+		MultiplicationMin1:    2,
+		MultiplicationMax1:    5,
+		MultiplicationMin2:    10,
+		MultiplicationMax2:    20,
+		DivisionMin1:          2,
+		DivisionMax1:          12,
+		DivisionMin2:          2,
+		DivisionMax2:          12,
+	}}
+
+	tests := []struct {
+		op       Operation
+		operands []int
+		fits     bool
+	}{
+		{Addition, []int{5, 50}, true},
+		{Addition, []int{50, 5}, false}, // addition keeps the operand order
+		{Subtraction, []int{55, 5}, true},
+		{Subtraction, []int{55, 50}, true},   // either part of the sum may be subtracted
+		{Subtraction, []int{100, 20}, false}, // neither part is in the first range
+		{Multiplication, []int{3, 15}, true},
+		{Multiplication, []int{15, 3}, true},
+		{Multiplication, []int{3, 4}, false},
+		{Division, []int{24, 2}, false}, // division is disabled
+		{Addition, []int{0, 50}, false},
+	}
+
+	for _, tt := range tests {
+		if got := Fits(settings, tt.op, tt.operands); got != tt.fits {
+			t.Errorf("Fits(%s, %v) = %v, want %v", tt.op, tt.operands, got, tt.fits)
+		}
+	}
+}
+
+func TestGeneratedProblemsFit(t *testing.T) {
+	settings := testSettings()
+	g := NewWithSource(settings, rand.NewSource(1))
+	for i := 0; i < 1000; i++ {
+		p := g.Generate()
+		if answer, ok := Answer(p.Operation, p.Operands); !ok || answer != p.Answer {
+			t.Fatalf("Answer(%q) = %d, %v; want %d", p.Question, answer, ok, p.Answer)
+		}
+		if !Fits(settings, p.Operation, p.Operands) {
+			t.Fatalf("%q doesn't fit the settings that generated it", p.Question)
+		}
+	}
+}
+
+func TestFactFits(t *testing.T) {
+	settings := testSettings()
+	tests := []struct {
+		fact Fact
+		fits bool
+	}{
+		{Fact{Division, 7, 85}, true},       // 595 ÷ 7
+		{Fact{Division, 50, 85}, false},     // both divisors are over 12
+		{Fact{Multiplication, 85, 7}, true}, // either order
+		{Fact{Multiplication, 13, 85}, false},
+		{Fact{Addition, 2, 100}, true},
+		{Fact{Addition, 1, 100}, false},
+	}
+
+	for _, tt := range tests {
+		if got := FactFits(settings, tt.fact); got != tt.fits {
+			t.Errorf("FactFits(%+v) = %v, want %v", tt.fact, got, tt.fits)
+		}
+	}
+}
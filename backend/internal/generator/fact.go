@@ -0,0 +1,32 @@
+package generator
+
+// Fact is the pair of numbers a problem drills, smaller first. Addition and
+// multiplication use their operands; subtraction and division use the
+// subtrahend or divisor and the answer, so 7 × 8, 8 × 7 and 56 ÷ 7 all drill
+// the fact 7, 8 of their operation.
+type Fact struct {
+	Operation Operation
+	A, B      int
+}
+
+// FactOf returns the fact a problem drills, or false if the problem doesn't
+// have two operands
+func FactOf(op Operation, operands []int, answer int) (Fact, bool) {
+	if len(operands) != 2 {
+		return Fact{}, false
+	}
+
+	a, b := operands[0], operands[1]
+	switch op {
+	case Subtraction, Division:
+		a, b = operands[1], answer
+	case Addition, Multiplication:
+	default:
+		return Fact{}, false
+	}
+
+	if b < a {
+		a, b = b, a
+	}
+	return Fact{Operation: op, A: a, B: b}, true
+}
@@ -0,0 +1,29 @@
+package generator
+
+import "testing"
+
+func TestFactOf(t *testing.T) {
+	tests := []struct {
+		op       Operation
+		operands []int
+		answer   int
+		fact     Fact
+		ok       bool
+	}{
+		{Multiplication, []int{7, 8}, 56, Fact{Multiplication, 7, 8}, true},
+		{Multiplication, []int{8, 7}, 56, Fact{Multiplication, 7, 8}, true},
+		{Addition, []int{9, 3}, 12, Fact{Addition, 3, 9}, true},
+		{Division, []int{56, 7}, 8, Fact{Division, 7, 8}, true},
+		{Division, []int{56, 8}, 7, Fact{Division, 7, 8}, true},
+		{Subtraction, []int{15, 7}, 8, Fact{Subtraction, 7, 8}, true},
+		{Multiplication, []int{7}, 7, Fact{}, false},
+		{"modulo", []int{7, 8}, 7, Fact{}, false},
+	}
+
+	for _, tt := range tests {
+		fact, ok := FactOf(tt.op, tt.operands, tt.answer)
+		if fact != tt.fact || ok != tt.ok {
+			t.Errorf("FactOf(%s, %v, %d) = %+v, %v; want %+v, %v", tt.op, tt.operands, tt.answer, fact, ok, tt.fact, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/calebwoo/mental-math-trainer/internal/models"
+)
+
+// fingerprintCodes are the short operation names used in fingerprints
+var fingerprintCodes = map[Operation]string{
+	Addition:       "add",
+	Subtraction:    "sub",
+	Multiplication: "mul",
+	Division:       "div",
+}
+
+// Fingerprint returns a canonical identifier for the problems a settings
+// configuration produces, such as "add:2-100:2-100,mul:2-12:2-100". Only
+// enabled operations are included, in a fixed order, so settings that
+// generate the same problems always share a fingerprint.
+func Fingerprint(settings models.Settings) string {
+	var parts []string
+	for _, op := range EnabledOperations(settings) {
+		r := OperandRanges(settings, op)
+		parts = append(parts, fmt.Sprintf("%s:%d-%d:%d-%d", fingerprintCodes[op], r[0], r[1], r[2], r[3]))
+	}
+	return strings.Join(parts, ",")
+}
+
+// ParseFingerprint rebuilds the problem configuration a fingerprint describes
+func ParseFingerprint(fingerprint string) (models.Settings, error) {
+	var settings models.Settings
+	seen := make(map[Operation]bool)
+
+	for _, part := range strings.Split(fingerprint, ",") {
+		i := strings.IndexByte(part, ':')
+		if i < 0 {
+			return settings, fmt.Errorf("invalid settings fingerprint")
+		}
+		code := part[:i]
+		var r [4]int
+		if _, err := fmt.Sscanf(part[i+1:], "%d-%d:%d-%d", &r[0], &r[1], &r[2], &r[3]); err != nil {
+			return settings, fmt.Errorf("invalid settings fingerprint")
+		}
+
+		op, ok := operationForCode(code)
+		if !ok || seen[op] || !validRanges(op, r) {
+			return settings, fmt.Errorf("invalid settings fingerprint")
+		}
+		seen[op] = true
+		setOperandRanges(&settings, op, r)
+	}
+
+	// Reject anything that doesn't round-trip, so each configuration has
+	// exactly one spelling
+	if Fingerprint(settings) != fingerprint {
+		return settings, fmt.Errorf("invalid settings fingerprint")
+	}
+	return settings, nil
+}
+
+// OperandRanges returns min1, max1, min2 and max2 for an operation
+func OperandRanges(settings models.Settings, op Operation) [4]int {
+	switch op {
+	case Addition:
+		return [4]int{settings.AdditionMin1, settings.AdditionMax1, settings.AdditionMin2, settings.AdditionMax2}
+	case Subtraction:
+		return [4]int{settings.SubtractionMin1, settings.SubtractionMax1, settings.SubtractionMin2, settings.SubtractionMax2}
+	case Division:
+		return [4]int{settings.DivisionMin1, settings.DivisionMax1, settings.DivisionMin2, settings.DivisionMax2}
+	default:
+		return [4]int{settings.MultiplicationMin1, settings.MultiplicationMax1, settings.MultiplicationMin2, settings.MultiplicationMax2}
+	}
+}
+
+// setOperandRanges enables an operation with the given ranges
+func setOperandRanges(settings *models.Settings, op Operation, r [4]int) {
+	switch op {
+	case Addition:
+		settings.AdditionEnabled = true
+		settings.AdditionMin1, settings.AdditionMax1, settings.AdditionMin2, settings.AdditionMax2 = r[0], r[1], r[2], r[3]
+	case Subtraction:
+		settings.SubtractionEnabled = true
+		settings.SubtractionMin1, settings.SubtractionMax1, settings.SubtractionMin2, settings.SubtractionMax2 = r[0], r[1], r[2], r[3]
+	case Multiplication:
+		settings.MultiplicationEnabled = true
+		settings.MultiplicationMin1, settings.MultiplicationMax1, settings.MultiplicationMin2, settings.MultiplicationMax2 = r[0], r[1], r[2], r[3]
+	case Division:
+		settings.DivisionEnabled = true
+		settings.DivisionMin1, settings.DivisionMax1, settings.DivisionMin2, settings.DivisionMax2 = r[0], r[1], r[2], r[3]
+	}
+}
+
+// validRanges applies the ValidateSettings range rules to one operation
+func validRanges(op Operation, r [4]int) bool {
+	for i := 0; i < 4; i += 2 {
+		if r[i] < 0 || r[i] > r[i+1] || r[i+1] > MaxOperand {
+			return false
+		}
+	}
+	return op != Division || r[0] > 0
+}
+
+func operationForCode(code string) (Operation, bool) {
+	for op, c := range fingerprintCodes {
+		if c == code {
+			return op, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,42 @@
+package generator
+
+import "testing"
+
+func TestFingerprintRoundTrip(t *testing.T) {
+	settings := testSettings()
+	settings.SubtractionEnabled = false
+
+	fingerprint := Fingerprint(settings)
+	if want := "add:2-100:2-100,mul:2-12:2-100,div:2-12:2-100"; fingerprint != want {
+		t.Fatalf("fingerprint = %q, want %q", fingerprint, want)
+	}
+
+	parsed, err := ParseFingerprint(fingerprint)
+	if err != nil {
+		t.Fatalf("ParseFingerprint(%q): %v", fingerprint, err)
+	}
+	if got := Fingerprint(parsed); got != fingerprint {
+		t.Fatalf("round trip gave %q, want %q", got, fingerprint)
+	}
+	if parsed.SubtractionEnabled {
+		t.Fatalf("subtraction enabled after round trip")
+	}
+}
+
+func TestParseFingerprintRejectsInvalid(t *testing.T) {
+	for _, fingerprint := range []string{
+		"",
+		"add",
+		"add:2-100",
+		"pow:2-10:2-10",
+		"add:100-2:2-100",
+		"div:0-12:2-100",
+		"add:2-100:2-100,add:2-100:2-100",
+		"mul:2-12:2-100,add:2-100:2-100", // operations out of order
+		"add:02-100:2-100",
+	} {
+		if _, err := ParseFingerprint(fingerprint); err == nil {
+			t.Errorf("ParseFingerprint(%q) succeeded, want an error", fingerprint)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package generator
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/calebwoo/mental-math-trainer/internal/models"
+)
+
+// Operation identifies the arithmetic operation of a problem
+type Operation string
+
+const (
+	Addition       Operation = "addition"
+	Subtraction    Operation = "subtraction"
+	Multiplication Operation = "multiplication"
+	Division       Operation = "division"
+)
+
+// Problem represents a generated math problem
+type Problem struct {
+	Question  string    `json:"question"`
+	Answer    int       `json:"answer"`
+	Operation Operation `json:"operation"`
+	Operands  []int     `json:"operands"` // in the order they appear in Question
+}
+
+// Generator produces random problems from a user's settings.
+// A Generator is not safe for concurrent use.
+type Generator struct {
+	settings models.Settings
+	rng      *rand.Rand
+}
+
+// New creates a generator seeded from the current time
+func New(settings models.Settings) *Generator {
+	return NewWithSource(settings, rand.NewSource(time.Now().UnixNano()))
+}
+
+// NewWithSource creates a generator using the given random source,
+// which makes the generated sequence reproducible
+func NewWithSource(settings models.Settings, src rand.Source) *Generator {
+	return &Generator{
+		settings: settings,
+		rng:      rand.New(src),
+	}
+}
+
+// EnabledOperations returns the operations enabled in the settings.
+// Multiplication is used when nothing is enabled, matching the frontend.
+func EnabledOperations(settings models.Settings) []Operation {
+	var ops []Operation
+	if settings.AdditionEnabled {
+		ops = append(ops, Addition)
+	}
+	if settings.SubtractionEnabled {
+		ops = append(ops, Subtraction)
+	}
+	if settings.MultiplicationEnabled {
+		ops = append(ops, Multiplication)
+	}
+	if settings.DivisionEnabled {
+		ops = append(ops, Division)
+	}
+
+	if len(ops) == 0 {
+		// Default to multiplication if nothing is enabled
+		ops = append(ops, Multiplication)
+	}
+	return ops
+}
+
+// Generate returns a problem for a randomly chosen enabled operation
+func (g *Generator) Generate() Problem {
+	ops := EnabledOperations(g.settings)
+	return g.GenerateOperation(ops[g.rng.Intn(len(ops))])
+}
+
+// GenerateOperation returns a problem for the given operation
+func (g *Generator) GenerateOperation(op Operation) Problem {
+	switch op {
+	case Addition:
+		return g.addition()
+	case Subtraction:
+		return g.subtraction()
+	case Division:
+		return g.division()
+	default:
+		return g.multiplication()
+	}
+}
+
+// GenerateFact returns a problem drilling the given fact, in either order.
+// An order the generator's settings couldn't have produced, such as a
+// divisor outside the divisor range, is only used if the other order
+// doesn't fit either. See FactOf for how facts map to problems.
+func (g *Generator) GenerateFact(fact Fact) Problem {
+	first := factProblem(fact.Operation, fact.A, fact.B)
+	second := factProblem(fact.Operation, fact.B, fact.A)
+	if g.rng.Intn(2) == 0 {
+		first, second = second, first
+	}
+
+	if !Fits(g.settings, first.Operation, first.Operands) && Fits(g.settings, second.Operation, second.Operands) {
+		return second
+	}
+	return first
+}
+
+// factProblem returns the problem drilling a fact with a as the first
+// operand of addition and multiplication, the subtrahend of subtraction and
+// the divisor of division
+func factProblem(op Operation, a, b int) Problem {
+	switch op {
+	case Addition:
+		return newProblem(Addition, a, b, a+b)
+	case Subtraction:
+		return newProblem(Subtraction, a+b, a, b)
+	case Division:
+		// Never divide by zero, whichever order was picked
+		if a == 0 {
+			a, b = b, a
+		}
+		if a == 0 {
+			a = 1
+		}
+		return newProblem(Division, a*b, a, b)
+	default:
+		return newProblem(Multiplication, a, b, a*b)
+	}
+}
+
+func (g *Generator) addition() Problem {
+	a := g.randomInt(g.settings.AdditionMin1, g.settings.AdditionMax1)
+	b := g.randomInt(g.settings.AdditionMin2, g.settings.AdditionMax2)
+	return newProblem(Addition, a, b, a+b)
+}
+
+// subtraction generates A and B from their respective ranges, then asks
+// (A+B) - A or (A+B) - B so the answer always falls inside a configured range
+func (g *Generator) subtraction() Problem {
+	a := g.randomInt(g.settings.SubtractionMin1, g.settings.SubtractionMax1)
+	b := g.randomInt(g.settings.SubtractionMin2, g.settings.SubtractionMax2)
+	sum := a + b
+
+	// Randomly choose to subtract either A or B from the sum
+	if g.rng.Intn(2) == 0 {
+		return newProblem(Subtraction, sum, a, b)
+	}
+	return newProblem(Subtraction, sum, b, a)
+}
+
+func (g *Generator) multiplication() Problem {
+	// First number from min1-max1, second from min2-max2
+	a := g.randomInt(g.settings.MultiplicationMin1, g.settings.MultiplicationMax1)
+	b := g.randomInt(g.settings.MultiplicationMin2, g.settings.MultiplicationMax2)
+
+	// Randomly decide the order
+	if g.rng.Intn(2) == 0 {
+		return newProblem(Multiplication, a, b, a*b)
+	}
+	return newProblem(Multiplication, b, a, a*b)
+}
+
+// division picks the divisor from min1-max1 and the quotient from min2-max2,
+// so the dividend is always an exact multiple of the divisor
+func (g *Generator) division() Problem {
+	divisor := g.randomInt(g.settings.DivisionMin1, g.settings.DivisionMax1)
+	quotient := g.randomInt(g.settings.DivisionMin2, g.settings.DivisionMax2)
+	if divisor == 0 {
+		divisor = 1
+	}
+	return newProblem(Division, divisor*quotient, divisor, quotient)
+}
+
+// randomInt returns a random integer in [min, max], tolerating swapped bounds
+func (g *Generator) randomInt(min, max int) int {
+	if max < min {
+		min, max = max, min
+	}
+	return g.rng.Intn(max-min+1) + min
+}
+
+func newProblem(op Operation, left, right, answer int) Problem {
+	return Problem{
+		Question:  fmt.Sprintf("%d %s %d", left, op.Symbol(), right),
+		Answer:    answer,
+		Operation: op,
+		Operands:  []int{left, right},
+	}
+}
+
+// Symbol returns the display symbol used in question strings
+func (op Operation) Symbol() string {
+	switch op {
+	case Addition:
+		return "+"
+	case Subtraction:
+		return "-"
+	case Multiplication:
+		return "×"
+	case Division:
+		return "÷"
+	default:
+		return "?"
+	}
+}
@@ -0,0 +1,166 @@
+package generator
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/calebwoo/mental-math-trainer/internal/models"
+)
+
+// testSettings matches the default settings served to new users
+func testSettings() models.Settings {
+	return models.Settings{OperationSettings: models.OperationSettings{
+		AdditionEnabled:       true,
+		AdditionMin1:          2,
+		AdditionMax1:          100,
+		AdditionMin2:          2,
+		AdditionMax2:          100,
+		SubtractionEnabled:    true,
+		SubtractionMin1:       2,
+		SubtractionMax1:       100,
+		SubtractionMin2:       2,
+		SubtractionMax2:       100,
+		MultiplicationEnabled: true,
+		MultiplicationMin1:    2,
+		MultiplicationMax1:    12,
+		MultiplicationMin2:    2,
+		MultiplicationMax2:    100,
+		DivisionEnabled:       true,
+		DivisionMin1:          2,
+		DivisionMax1:          12,
+		DivisionMin2:          2,
+		DivisionMax2:          100,
+	}}
+}
+
+// checkProblem returns why a generated problem is wrong or outside the
+// settings, or "" if it is fine
+func checkProblem(settings models.Settings, p Problem) string {
+	if len(p.Operands) != 2 {
+		return fmt.Sprintf("has operands %v", p.Operands)
+	}
+	left, right := p.Operands[0], p.Operands[1]
+	if want := fmt.Sprintf("%d %s %d", left, p.Operation.Symbol(), right); p.Question != want {
+		return fmt.Sprintf("doesn't read %q", want)
+	}
+
+	in := func(n, min, max int) bool { return n >= min && n <= max }
+	s := settings
+	switch p.Operation {
+	case Addition:
+		if p.Answer != left+right {
+			return "has the wrong answer"
+		}
+		if !in(left, s.AdditionMin1, s.AdditionMax1) || !in(right, s.AdditionMin2, s.AdditionMax2) {
+			return "is outside the addition ranges"
+		}
+	case Subtraction:
+		// The subtrahend and answer are the two numbers picked from the ranges
+		if p.Answer != left-right {
+			return "has the wrong answer"
+		}
+		if !(in(right, s.SubtractionMin1, s.SubtractionMax1) && in(p.Answer, s.SubtractionMin2, s.SubtractionMax2)) &&
+			!(in(p.Answer, s.SubtractionMin1, s.SubtractionMax1) && in(right, s.SubtractionMin2, s.SubtractionMax2)) {
+			return "is outside the subtraction ranges"
+		}
+	case Multiplication:
+		if p.Answer != left*right {
+			return "has the wrong answer"
+		}
+		if !(in(left, s.MultiplicationMin1, s.MultiplicationMax1) && in(right, s.MultiplicationMin2, s.MultiplicationMax2)) &&
+			!(in(right, s.MultiplicationMin1, s.MultiplicationMax1) && in(left, s.MultiplicationMin2, s.MultiplicationMax2)) {
+			return "is outside the multiplication ranges"
+		}
+	case Division:
+		if right == 0 || left != right*p.Answer {
+			return "has the wrong answer"
+		}
+		if !in(right, s.DivisionMin1, s.DivisionMax1) || !in(p.Answer, s.DivisionMin2, s.DivisionMax2) {
+			return "is outside the division ranges"
+		}
+	default:
+		return "has an unknown operation"
+	}
+	return ""
+}
+
+func TestGenerateStaysInSettings(t *testing.T) {
+	narrow := models.Settings{OperationSettings: models.OperationSettings{
+		SubtractionEnabled: true,
+		SubtractionMin1:    5,
+		SubtractionMax1:    9,
+		SubtractionMin2:    20,
+		SubtractionMax2:    30,
+		DivisionEnabled:    true,
+		DivisionMin1:       3,
+		DivisionMax1:       3,
+		DivisionMin2:       7,
+		DivisionMax2:       8,
+	}}
+
+	for name, settings := range map[string]models.Settings{"default": testSettings(), "narrow": narrow} {
+		g := NewWithSource(settings, rand.NewSource(1))
+		seen := make(map[Operation]bool)
+		for i := 0; i < 1000; i++ {
+			p := g.Generate()
+			if problem := checkProblem(settings, p); problem != "" {
+				t.Fatalf("%s: %q %s", name, p.Question, problem)
+			}
+			seen[p.Operation] = true
+		}
+		if ops := EnabledOperations(settings); len(seen) != len(ops) {
+			t.Errorf("%s: generated %v, want every one of %v", name, seen, ops)
+		}
+	}
+}
+
+func TestGenerateOperation(t *testing.T) {
+	g := NewWithSource(testSettings(), rand.NewSource(1))
+	for _, op := range []Operation{Addition, Subtraction, Multiplication, Division} {
+		if p := g.GenerateOperation(op); p.Operation != op {
+			t.Errorf("GenerateOperation(%s) gave a %s problem", op, p.Operation)
+		}
+	}
+}
+
+func TestGenerateIsReproducible(t *testing.T) {
+	a := NewWithSource(testSettings(), rand.NewSource(42))
+	b := NewWithSource(testSettings(), rand.NewSource(42))
+	for i := 0; i < 100; i++ {
+		if pa, pb := a.Generate(), b.Generate(); pa.Question != pb.Question {
+			t.Fatalf("problem %d: %q != %q", i+1, pa.Question, pb.Question)
+		}
+	}
+}
+
+func TestEnabledOperationsDefaultsToMultiplication(t *testing.T) {
+	ops := EnabledOperations(models.Settings{})
+	if len(ops) != 1 || ops[0] != Multiplication {
+		t.Fatalf("operations = %v, want [multiplication]", ops)
+	}
+}
+
+func TestGenerateFactKeepsToSettings(t *testing.T) {
+	settings := testSettings()
+	settings.AdditionMin1, settings.AdditionMax1 = 1, 9
+	settings.AdditionMin2, settings.AdditionMax2 = 10, 99
+
+	g := NewWithSource(settings, rand.NewSource(1))
+	for _, fact := range []Fact{{Division, 7, 85}, {Addition, 5, 50}} {
+		for i := 0; i < 20; i++ {
+			if p := g.GenerateFact(fact); !Fits(settings, p.Operation, p.Operands) {
+				t.Fatalf("GenerateFact(%+v) asked %q, outside the settings", fact, p.Question)
+			}
+		}
+	}
+
+	// Facts that fit either way are asked both ways
+	seen := make(map[string]bool)
+	for i := 0; i < 20; i++ {
+		seen[g.GenerateFact(Fact{Multiplication, 6, 7}).Question] = true
+	}
+	if !seen["6 × 7"] || !seen["7 × 6"] {
+		t.Errorf("GenerateFact(6, 7) asked %v, want both orders", seen)
+	}
+}
@@ -0,0 +1,66 @@
+package generator
+
+import (
+	"strconv"
+	"strings"
+)
+
+// questionSymbols maps the operators accepted in question strings to their
+// operation; ASCII forms are accepted alongside the display symbols
+var questionSymbols = map[string]Operation{
+	"+": Addition,
+	"-": Subtraction,
+	"−": Subtraction,
+	"×": Multiplication,
+	"*": Multiplication,
+	"x": Multiplication,
+	"÷": Division,
+	"/": Division,
+}
+
+// ParseQuestion reads the operation and operands from a question string such
+// as "144 ÷ 12". It returns false if the question isn't a binary problem.
+func ParseQuestion(question string) (Operation, []int, bool) {
+	fields := strings.Fields(question)
+	if len(fields) != 3 {
+		return "", nil, false
+	}
+
+	op, ok := questionSymbols[fields[1]]
+	if !ok {
+		return "", nil, false
+	}
+
+	left, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return "", nil, false
+	}
+	right, err := strconv.Atoi(fields[2])
+	if err != nil {
+		return "", nil, false
+	}
+	return op, []int{left, right}, true
+}
+
+// ParseOperation returns the operation with the given name, such as "division"
+func ParseOperation(name string) (Operation, bool) {
+	switch op := Operation(name); op {
+	case Addition, Subtraction, Multiplication, Division:
+		return op, true
+	default:
+		return "", false
+	}
+}
+
+// Difficulty describes a problem by the number of digits in each operand,
+// such as "3x2" for 144 ÷ 12
+func Difficulty(operands []int) string {
+	parts := make([]string, len(operands))
+	for i, operand := range operands {
+		if operand < 0 {
+			operand = -operand
+		}
+		parts[i] = strconv.Itoa(len(strconv.Itoa(operand)))
+	}
+	return strings.Join(parts, "x")
+}
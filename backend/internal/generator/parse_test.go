@@ -0,0 +1,51 @@
+package generator
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestParseQuestion(t *testing.T) {
+	tests := []struct {
+		question string
+		op       Operation
+		operands []int
+		ok       bool
+	}{
+		{"12 + 7", Addition, []int{12, 7}, true},
+		{"20 - 8", Subtraction, []int{20, 8}, true},
+		{"20 − 8", Subtraction, []int{20, 8}, true},
+		{"6 × 7", Multiplication, []int{6, 7}, true},
+		{"6 * 7", Multiplication, []int{6, 7}, true},
+		{"6 x 7", Multiplication, []int{6, 7}, true},
+		{"144 ÷ 12", Division, []int{144, 12}, true},
+		{"144 / 12", Division, []int{144, 12}, true},
+		{"12+7", "", nil, false},
+		{"12 % 7", "", nil, false},
+		{"a + 7", "", nil, false},
+		{"1 + 2 + 3", "", nil, false},
+		{"", "", nil, false},
+	}
+
+	for _, tt := range tests {
+		op, operands, ok := ParseQuestion(tt.question)
+		if ok != tt.ok || op != tt.op {
+			t.Errorf("ParseQuestion(%q) = %s, %v, %v; want %s, %v, %v", tt.question, op, operands, ok, tt.op, tt.operands, tt.ok)
+			continue
+		}
+		if ok && (operands[0] != tt.operands[0] || operands[1] != tt.operands[1]) {
+			t.Errorf("ParseQuestion(%q) operands = %v, want %v", tt.question, operands, tt.operands)
+		}
+	}
+}
+
+func TestParseGeneratedQuestions(t *testing.T) {
+	g := NewWithSource(testSettings(), rand.NewSource(1))
+	for i := 0; i < 200; i++ {
+		p := g.Generate()
+		op, operands, ok := ParseQuestion(p.Question)
+		if !ok || op != p.Operation || operands[0] != p.Operands[0] || operands[1] != p.Operands[1] {
+			t.Fatalf("ParseQuestion(%q) = %s, %v, %v; want %s, %v", p.Question, op, operands, ok, p.Operation, p.Operands)
+		}
+	}
+}
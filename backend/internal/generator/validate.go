@@ -0,0 +1,77 @@
+package generator
+
+import (
+	"fmt"
+
+	"github.com/calebwoo/mental-math-trainer/internal/models"
+)
+
+// MaxOperand bounds every configured range so answers stay well inside int range
+const MaxOperand = 1000000
+
+// Field error codes
+const (
+	CodeNegative          = "negative"
+	CodeTooLarge          = "too_large"
+	CodeMinGreaterThanMax = "min_greater_than_max"
+	CodeZeroDivisor       = "zero_divisor"
+	CodeNoOperation       = "no_operation_enabled"
+)
+
+// operandRange is one configurable min/max pair in the settings
+type operandRange struct {
+	op       string // operation name, used as the JSON field prefix
+	operand  string // "1" or "2"
+	min, max int
+}
+
+// ValidateSettings checks every operation range in the settings and returns
+// one error per problem found, or nil if the settings are usable
+func ValidateSettings(settings models.Settings) []models.FieldError {
+	var errs []models.FieldError
+
+	ranges := []operandRange{
+		{"addition", "1", settings.AdditionMin1, settings.AdditionMax1},
+		{"addition", "2", settings.AdditionMin2, settings.AdditionMax2},
+		{"subtraction", "1", settings.SubtractionMin1, settings.SubtractionMax1},
+		{"subtraction", "2", settings.SubtractionMin2, settings.SubtractionMax2},
+		{"multiplication", "1", settings.MultiplicationMin1, settings.MultiplicationMax1},
+		{"multiplication", "2", settings.MultiplicationMin2, settings.MultiplicationMax2},
+		{"division", "1", settings.DivisionMin1, settings.DivisionMax1},
+		{"division", "2", settings.DivisionMin2, settings.DivisionMax2},
+	}
+
+	for _, r := range ranges {
+		minField := r.op + "_min" + r.operand
+		maxField := r.op + "_max" + r.operand
+
+		if r.min < 0 {
+			errs = append(errs, fieldError(minField, CodeNegative, "must not be negative"))
+		}
+		if r.max < 0 {
+			errs = append(errs, fieldError(maxField, CodeNegative, "must not be negative"))
+		}
+		if r.max > MaxOperand {
+			errs = append(errs, fieldError(maxField, CodeTooLarge, fmt.Sprintf("must be at most %d", MaxOperand)))
+		}
+		if r.min > r.max {
+			errs = append(errs, fieldError(minField, CodeMinGreaterThanMax, fmt.Sprintf("must not be greater than %s", maxField)))
+		}
+	}
+
+	// The first division range is the divisor, so it can't include zero
+	if settings.DivisionMin1 == 0 {
+		errs = append(errs, fieldError("division_min1", CodeZeroDivisor, "divisor range must not include zero"))
+	}
+
+	if !settings.AdditionEnabled && !settings.SubtractionEnabled &&
+		!settings.MultiplicationEnabled && !settings.DivisionEnabled {
+		errs = append(errs, fieldError("operations", CodeNoOperation, "at least one operation must be enabled"))
+	}
+
+	return errs
+}
+
+func fieldError(field, code, message string) models.FieldError {
+	return models.FieldError{Field: field, Code: code, Message: message}
+}
@@ -0,0 +1,89 @@
+package generator
+
+import (
+	"testing"
+
+	"github.com/calebwoo/mental-math-trainer/internal/models"
+)
+
+func TestValidateSettingsAcceptsDefaults(t *testing.T) {
+	if errs := ValidateSettings(testSettings()); errs != nil {
+		t.Fatalf("errors = %v, want none", errs)
+	}
+}
+
+func TestValidateSettings(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(*models.Settings)
+		want   []models.FieldError
+	}{
+		{
+			name:   "negative minimum",
+			change: func(s *models.Settings) { s.AdditionMin1 = -1 },
+			want:   []models.FieldError{{Field: "addition_min1", Code: CodeNegative}},
+		},
+		{
+			name:   "negative maximum",
+			change: func(s *models.Settings) { s.SubtractionMin2, s.SubtractionMax2 = 0, -1 },
+			want: []models.FieldError{
+				{Field: "subtraction_max2", Code: CodeNegative},
+				{Field: "subtraction_min2", Code: CodeMinGreaterThanMax},
+			},
+		},
+		{
+			name:   "maximum too large",
+			change: func(s *models.Settings) { s.MultiplicationMax2 = MaxOperand + 1 },
+			want:   []models.FieldError{{Field: "multiplication_max2", Code: CodeTooLarge}},
+		},
+		{
+			name:   "minimum greater than maximum",
+			change: func(s *models.Settings) { s.AdditionMin2 = 101 },
+			want:   []models.FieldError{{Field: "addition_min2", Code: CodeMinGreaterThanMax}},
+		},
+		{
+			name:   "zero divisor",
+			change: func(s *models.Settings) { s.DivisionMin1 = 0 },
+			want:   []models.FieldError{{Field: "division_min1", Code: CodeZeroDivisor}},
+		},
+		{
+			// Disabled operations are still checked, as they can be enabled later
+			name: "zero divisor while division is disabled",
+			change: func(s *models.Settings) {
+				s.DivisionEnabled = false
+				s.DivisionMin1 = 0
+			},
+			want: []models.FieldError{{Field: "division_min1", Code: CodeZeroDivisor}},
+		},
+		{
+			name: "no operation enabled",
+			change: func(s *models.Settings) {
+				s.AdditionEnabled = false
+				s.SubtractionEnabled = false
+				s.MultiplicationEnabled = false
+				s.DivisionEnabled = false
+			},
+			want: []models.FieldError{{Field: "operations", Code: CodeNoOperation}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settings := testSettings()
+			tt.change(&settings)
+
+			errs := ValidateSettings(settings)
+			if len(errs) != len(tt.want) {
+				t.Fatalf("errors = %v, want %v", errs, tt.want)
+			}
+			for i, err := range errs {
+				if err.Field != tt.want[i].Field || err.Code != tt.want[i].Code {
+					t.Errorf("error %d = %s/%s, want %s/%s", i, err.Field, err.Code, tt.want[i].Field, tt.want[i].Code)
+				}
+				if err.Message == "" {
+					t.Errorf("error %d has no message", i)
+				}
+			}
+		})
+	}
+}
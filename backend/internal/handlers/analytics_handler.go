@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/calebwoo/mental-math-trainer/internal/analytics"
+	"github.com/calebwoo/mental-math-trainer/internal/generator"
+	"github.com/calebwoo/mental-math-trainer/internal/repository"
+	"github.com/gin-gonic/gin"
+)
+
+// dateLayout is the format of the analytics date range parameters
+const dateLayout = "2006-01-02"
+
+// GetAnalytics breaks the current user's answered problems down by operation.
+// It accepts ?from= and ?to= dates (YYYY-MM-DD, inclusive) in the ?tz= time
+// zone (default UTC), and ?session_id=<id>[,<id>...] to limit it to sessions.
+func (h *Handler) GetAnalytics(c *gin.Context) {
+	filter, ok := parseProblemFilter(c)
+	if !ok {
+		return
+	}
+
+	problems, err := h.problems.ListAnsweredByUser(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch analytics"})
+		return
+	}
+
+	c.JSON(http.StatusOK, analytics.ByOperation(problems))
+}
+
+// GetFactHeatmap arranges the current user's answered problems of one
+// operation into a grid of facts for rendering as a heatmap. It accepts
+// ?operation= (default multiplication) and the same filters as GetAnalytics.
+func (h *Handler) GetFactHeatmap(c *gin.Context) {
+	op, valid := generator.ParseOperation(c.DefaultQuery("operation", string(generator.Multiplication)))
+	if !valid {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "operation must be addition, subtraction, multiplication or division"})
+		return
+	}
+
+	filter, ok := parseProblemFilter(c)
+	if !ok {
+		return
+	}
+	filter.Operation = string(op)
+
+	problems, err := h.problems.ListAnsweredByUser(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch analytics"})
+		return
+	}
+
+	c.JSON(http.StatusOK, analytics.Heatmap(op, problems))
+}
+
+// parseProblemFilter reads the date range and session filters for the
+// current user. On failure the error response is written and ok is false.
+func parseProblemFilter(c *gin.Context) (filter repository.ProblemFilter, ok bool) {
+	filter.UserID, ok = currentUserID(c)
+	if !ok {
+		return filter, false
+	}
+
+	loc, ok := parseLocation(c)
+	if !ok {
+		return filter, false
+	}
+
+	if raw := c.Query("from"); raw != "" {
+		from, err := time.ParseInLocation(dateLayout, raw, loc)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from must be a date like 2024-01-31"})
+			return filter, false
+		}
+		filter.From = &from
+	}
+
+	if raw := c.Query("to"); raw != "" {
+		to, err := time.ParseInLocation(dateLayout, raw, loc)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "to must be a date like 2024-01-31"})
+			return filter, false
+		}
+		// The range includes the whole of the last day
+		to = to.AddDate(0, 0, 1)
+		filter.To = &to
+	}
+
+	if filter.From != nil && filter.To != nil && !filter.From.Before(*filter.To) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must not be after to"})
+		return filter, false
+	}
+
+	if raw := c.Query("session_id"); raw != "" {
+		for _, part := range strings.Split(raw, ",") {
+			id, ok := parseID(strings.TrimSpace(part))
+			if !ok || id == 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session ID"})
+				return filter, false
+			}
+			filter.SessionIDs = append(filter.SessionIDs, id)
+		}
+	}
+
+	return filter, true
+}
@@ -1,12 +1,12 @@
 package handlers
 
 import (
-	"fmt"
+	"log"
 	"net/http"
 	"os"
 	"time"
 
-	"github.com/calebwoo/mental-math-trainer/internal/database"
+	"github.com/calebwoo/mental-math-trainer/internal/middleware"
 	"github.com/calebwoo/mental-math-trainer/internal/models"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
@@ -35,8 +35,19 @@ func generateToken(userID uint, username string) (string, error) {
 	return token.SignedString(jwtSecret)
 }
 
+// generateAnonymousToken generates a long-lived JWT identifying an anonymous player
+func generateAnonymousToken(anonymousID string) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"typ":          middleware.AnonymousTokenType,
+		"anonymous_id": anonymousID,
+		"exp":          time.Now().Add(time.Hour * 24 * 365).Unix(), // 1 year
+	})
+
+	return token.SignedString(jwtSecret)
+}
+
 // Register handles user registration
-func Register(c *gin.Context) {
+func (h *Handler) Register(c *gin.Context) {
 	var req models.RegisterRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -44,8 +55,12 @@ func Register(c *gin.Context) {
 	}
 
 	// Check if username or email already exists
-	var existingUser models.User
-	if err := database.DB.Where("username = ? OR email = ?", req.Username, req.Email).First(&existingUser).Error; err == nil {
+	exists, err := h.users.ExistsByUsernameOrEmail(req.Username, req.Email)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check existing users"})
+		return
+	}
+	if exists {
 		c.JSON(http.StatusConflict, gin.H{"error": "Username or email already exists"})
 		return
 	}
@@ -64,7 +79,7 @@ func Register(c *gin.Context) {
 		PasswordHash: string(hashedPassword),
 	}
 
-	if err := database.DB.Create(&user).Error; err != nil {
+	if err := h.users.Create(&user); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
 		return
 	}
@@ -77,13 +92,14 @@ func Register(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusCreated, models.AuthResponse{
-		Token: token,
-		User:  user,
+		Token:           token,
+		User:            user,
+		ClaimedSessions: h.claimAnonymousSessions(user.ID, req.AnonymousClaims),
 	})
 }
 
 // Login handles user login
-func Login(c *gin.Context) {
+func (h *Handler) Login(c *gin.Context) {
 	var req models.LoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -91,8 +107,8 @@ func Login(c *gin.Context) {
 	}
 
 	// Find user by username
-	var user models.User
-	if err := database.DB.Where("username = ?", req.Username).First(&user).Error; err != nil {
+	user, err := h.users.FindByUsername(req.Username)
+	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid username or password"})
 		return
 	}
@@ -111,67 +127,60 @@ func Login(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, models.AuthResponse{
-		Token: token,
-		User:  user,
+		Token:           token,
+		User:            *user,
+		ClaimedSessions: h.claimAnonymousSessions(user.ID, req.AnonymousClaims),
 	})
 }
 
-// GetCurrentUser gets the current user from the token
-func GetCurrentUser(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
-		return
+// claimAnonymousSessions moves anonymous sessions onto a user's account, so
+// their history and leaderboard entries carry over. Sessions are claimed by
+// anonymous identity or by individual session secrets; invalid proof is
+// ignored rather than failing the login. Returns the number of sessions claimed.
+func (h *Handler) claimAnonymousSessions(userID uint, claims models.AnonymousClaims) int {
+	claimed := 0
+
+	if anonymousID := middleware.ParseAnonymousToken(claims.AnonymousToken); anonymousID != "" {
+		count, err := h.sessions.ClaimAnonymous(anonymousID, userID)
+		if err != nil {
+			log.Printf("Failed to claim sessions for anonymous player: %v", err)
+		} else {
+			claimed += count
+		}
 	}
 
-	var user models.User
-	if err := database.DB.First(&user, userID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
-		return
+	for _, claim := range claims.SessionClaims {
+		session, err := h.sessions.FindByID(claim.SessionID)
+		if err != nil || session.UserID != nil {
+			continue
+		}
+		if !sessionSecretMatches(session, claim.SessionSecret) {
+			continue
+		}
+
+		session.UserID = &userID
+		if err := h.sessions.Update(session); err != nil {
+			log.Printf("Failed to claim session %d: %v", session.ID, err)
+			continue
+		}
+		claimed++
 	}
 
-	c.JSON(http.StatusOK, user)
+	return claimed
 }
 
-// GetLeaderboard returns the top 10 highest scores for default settings only
-func GetLeaderboard(c *gin.Context) {
-	var sessions []models.Session
-
-	// Query top 10 sessions by score for default settings only, including user data
-	if err := database.DB.
-		Preload("User").
-		Where("is_default_settings = ?", true).
-		Order("score DESC").
-		Limit(10).
-		Find(&sessions).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch leaderboard"})
+// GetCurrentUser gets the current user from the token
+func (h *Handler) GetCurrentUser(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
 		return
 	}
 
-	// Convert to leaderboard entries
-	entries := make([]models.LeaderboardEntry, len(sessions))
-	for i, session := range sessions {
-		entry := models.LeaderboardEntry{
-			Rank:      i + 1,
-			Score:     session.Score,
-			Duration:  session.Duration,
-			StartedAt: session.StartedAt,
-		}
-
-		// Set username - either from user or anonymous name
-		if session.User != nil {
-			entry.Username = session.User.Username
-			entry.IsAnonymous = false
-		} else {
-			entry.Username = session.AnonymousName
-			if entry.Username == "" {
-				entry.Username = fmt.Sprintf("Anonymous Player #%d", session.ID)
-			}
-			entry.IsAnonymous = true
-		}
-
-		entries[i] = entry
+	user, err := h.users.FindByID(userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
 	}
 
-	c.JSON(http.StatusOK, entries)
-}
\ No newline at end of file
+	c.JSON(http.StatusOK, user)
+}
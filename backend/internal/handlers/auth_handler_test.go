@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/calebwoo/mental-math-trainer/internal/middleware"
+	"github.com/calebwoo/mental-math-trainer/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+func TestAuthClaimsAnonymousSessions(t *testing.T) {
+	a := newTestAPI(t)
+	_, alice := a.user("alice")
+
+	// One anonymous player with two sessions, another with one, and a
+	// session that already belongs to alice
+	first := a.createSession(nil)
+	second := a.createSession(map[string]string{middleware.AnonymousTokenHeader: first.AnonymousToken})
+	other := a.createSession(nil)
+	owned := a.createSession(bearer(alice))
+
+	// Registering claims the player's sessions by their anonymous token;
+	// a wrong secret claims nothing
+	var registered models.AuthResponse
+	status := a.do(http.MethodPost, "/api/auth/register", gin.H{
+		"username":        "carol",
+		"email":           "carol@example.com",
+		"password":        "secret123",
+		"anonymous_token": first.AnonymousToken,
+		"session_claims":  []gin.H{{"session_id": other.SessionID, "session_secret": "not-the-secret"}},
+	}, nil, &registered)
+	if status != http.StatusCreated {
+		t.Fatalf("register: status %d", status)
+	}
+	if registered.ClaimedSessions != 2 {
+		t.Errorf("register claimed %d sessions, want 2", registered.ClaimedSessions)
+	}
+	for _, id := range []uint{first.SessionID, second.SessionID} {
+		if status := a.do(http.MethodGet, fmt.Sprintf("/api/sessions/%d", id), nil, bearer(registered.Token), nil); status != http.StatusOK {
+			t.Errorf("carol GET session %d: status %d, want 200", id, status)
+		}
+	}
+
+	// Logging in claims a session by its secret, but never another user's
+	// session, and bad proof doesn't fail the login
+	var loggedIn models.AuthResponse
+	status = a.do(http.MethodPost, "/api/auth/login", gin.H{
+		"username":        "carol",
+		"password":        "secret123",
+		"anonymous_token": "not-a-token",
+		"session_claims": []gin.H{
+			{"session_id": other.SessionID, "session_secret": other.SessionSecret},
+			{"session_id": owned.SessionID, "session_secret": other.SessionSecret},
+		},
+	}, nil, &loggedIn)
+	if status != http.StatusOK {
+		t.Fatalf("login: status %d", status)
+	}
+	if loggedIn.ClaimedSessions != 1 {
+		t.Errorf("login claimed %d sessions, want 1", loggedIn.ClaimedSessions)
+	}
+	if status := a.do(http.MethodGet, fmt.Sprintf("/api/sessions/%d", other.SessionID), nil, bearer(loggedIn.Token), nil); status != http.StatusOK {
+		t.Errorf("carol GET claimed session: status %d, want 200", status)
+	}
+	if status := a.do(http.MethodGet, fmt.Sprintf("/api/sessions/%d", owned.SessionID), nil, bearer(alice), nil); status != http.StatusOK {
+		t.Errorf("alice GET her session: status %d, want 200", status)
+	}
+
+	// Claimed sessions no longer open with the anonymous proof
+	if status := a.do(http.MethodGet, fmt.Sprintf("/api/sessions/%d", first.SessionID), nil, map[string]string{sessionSecretHeader: first.SessionSecret}, nil); status != http.StatusForbidden {
+		t.Errorf("GET claimed session with its secret: status %d, want 403", status)
+	}
+}
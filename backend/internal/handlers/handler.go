@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/calebwoo/mental-math-trainer/internal/repository"
+	"github.com/gin-gonic/gin"
+)
+
+// Handler serves the API from a set of repositories
+type Handler struct {
+	users    repository.UserRepository
+	sessions repository.SessionRepository
+	problems repository.ProblemRepository
+	settings repository.SettingsRepository
+	facts    repository.FactRepository
+	grace    time.Duration // How long after its deadline a session still takes answers
+}
+
+// New creates a handler backed by the given repositories. Sessions accept
+// answers for the grace period after their time runs out, to allow for
+// network delay.
+func New(repos repository.Repositories, grace time.Duration) *Handler {
+	return &Handler{
+		users:    repos.Users,
+		sessions: repos.Sessions,
+		problems: repos.Problems,
+		settings: repos.Settings,
+		facts:    repos.Facts,
+		grace:    grace,
+	}
+}
+
+// parseID parses a numeric route parameter such as a session or problem ID
+func parseID(value string) (uint, bool) {
+	id, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// currentUserID returns the ID of the signed-in user. If there is none, the
+// 401 response is written and ok is false.
+func currentUserID(c *gin.Context) (uint, bool) {
+	value, exists := c.Get("user_id")
+	userID, ok := value.(uint)
+	if !exists || !ok || userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return 0, false
+	}
+	return userID, true
+}
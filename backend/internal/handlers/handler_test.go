@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/calebwoo/mental-math-trainer/internal/middleware"
+	"github.com/calebwoo/mental-math-trainer/internal/models"
+	"github.com/calebwoo/mental-math-trainer/internal/repository"
+	"github.com/gin-gonic/gin"
+)
+
+// testGrace is how long after their time limit test sessions accept problems
+const testGrace = 10 * time.Second
+
+// testAPI serves the API from in-memory repositories, with routes and
+// middleware wired as in main
+type testAPI struct {
+	t      *testing.T
+	repos  repository.Repositories
+	router *gin.Engine
+}
+
+func newTestAPI(t *testing.T) *testAPI {
+	gin.SetMode(gin.TestMode)
+	repos := repository.NewMemory()
+	h := New(repos, testGrace)
+
+	router := gin.New()
+	api := router.Group("/api")
+	api.POST("/auth/register", h.Register)
+	api.POST("/auth/login", h.Login)
+	api.GET("/leaderboard", h.GetLeaderboard)
+
+	optionalAuth := api.Group("/")
+	optionalAuth.Use(middleware.OptionalAuth())
+	optionalAuth.POST("/sessions", h.CreateSession)
+	optionalAuth.GET("/sessions/:id", h.GetSession)
+	optionalAuth.PATCH("/sessions/:id/complete", h.CompleteSession)
+	optionalAuth.DELETE("/sessions/:id", h.DeleteSession)
+	optionalAuth.GET("/sessions", h.GetSessions)
+	optionalAuth.POST("/sessions/:id/problems", h.SubmitProblem)
+	optionalAuth.POST("/sessions/:id/next", h.NextProblem)
+	optionalAuth.POST("/sessions/:id/problems/:problemId/answer", h.AnswerProblem)
+
+	protected := api.Group("/")
+	protected.Use(middleware.RequireAuth())
+	protected.GET("/leaderboard/me", h.GetLeaderboardPosition)
+	protected.GET("/settings", h.GetSettings)
+	protected.PUT("/settings", h.UpdateSettings)
+
+	return &testAPI{t: t, repos: repos, router: router}
+}
+
+// user creates a user and returns their ID and bearer token
+func (a *testAPI) user(username string) (uint, string) {
+	user := models.User{Username: username, Email: username + "@example.com"}
+	if err := a.repos.Users.Create(&user); err != nil {
+		a.t.Fatalf("create user: %v", err)
+	}
+	token, err := generateToken(user.ID, user.Username)
+	if err != nil {
+		a.t.Fatalf("generate token: %v", err)
+	}
+	return user.ID, token
+}
+
+// do sends a request with the given headers and decodes a successful JSON
+// response into out, if it isn't nil
+func (a *testAPI) do(method, path string, body any, headers map[string]string, out any) int {
+	var reader bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&reader).Encode(body); err != nil {
+			a.t.Fatalf("encode request: %v", err)
+		}
+	}
+	req := httptest.NewRequest(method, path, &reader)
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	w := httptest.NewRecorder()
+	a.router.ServeHTTP(w, req)
+	if out != nil && w.Code < 300 {
+		if err := json.Unmarshal(w.Body.Bytes(), out); err != nil {
+			a.t.Fatalf("%s %s: decode %q: %v", method, path, w.Body.String(), err)
+		}
+	}
+	return w.Code
+}
+
+func bearer(token string) map[string]string {
+	return map[string]string{"Authorization": "Bearer " + token}
+}
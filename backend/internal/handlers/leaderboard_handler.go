@@ -0,0 +1,333 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/calebwoo/mental-math-trainer/internal/generator"
+	"github.com/calebwoo/mental-math-trainer/internal/models"
+	"github.com/calebwoo/mental-math-trainer/internal/repository"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultLeaderboardLimit = 10
+	maxLeaderboardLimit     = 100
+	defaultAroundCount      = 5
+	maxAroundCount          = 50
+)
+
+// GetLeaderboard returns the highest scores for default settings, or for the
+// configuration given by ?settings=<fingerprint>, among sessions of
+// ?duration=<seconds> (default 120). With ?session_mode=sprint it ranks
+// finished sprints of ?target_count=<n> (default 20) by fastest time instead.
+// It accepts ?window=day|week|month|all (default all), ?tz=<IANA zone> for
+// the window boundaries (default UTC) and ?limit=<n> (default 10, max 100).
+// With ?view=players each player appears once, ranked by their best session.
+func (h *Handler) GetLeaderboard(c *gin.Context) {
+	query, ok := parseLeaderboardQuery(c)
+	if !ok {
+		return
+	}
+
+	switch c.DefaultQuery("view", "sessions") {
+	case "sessions":
+	case "players":
+		h.getPlayerLeaderboard(c, query)
+		return
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "view must be sessions or players"})
+		return
+	}
+
+	// Query top sessions by score for default settings only, including user data
+	sessions, err := h.sessions.Leaderboard(query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch leaderboard"})
+		return
+	}
+
+	// Convert to leaderboard entries
+	entries := make([]models.LeaderboardEntry, len(sessions))
+	for i, session := range sessions {
+		entry := models.LeaderboardEntry{
+			Rank:      i + 1,
+			Score:     session.Score,
+			Duration:  session.Duration,
+			StartedAt: session.StartedAt,
+		}
+
+		entry.Username, entry.IsAnonymous = displayName(session)
+		entries[i] = entry
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
+
+// getPlayerLeaderboard ranks each registered user and anonymous identity by
+// their best session
+func (h *Handler) getPlayerLeaderboard(c *gin.Context, query repository.LeaderboardQuery) {
+	standings, err := h.sessions.PlayerLeaderboard(query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch leaderboard"})
+		return
+	}
+
+	c.JSON(http.StatusOK, playerEntries(standings, 0, 0))
+}
+
+// GetLeaderboardPosition returns the caller's rank and percentile on the
+// players leaderboard, with up to ?around=<n> players (default 5, max 50)
+// above and below them. It accepts the same window and tz parameters as
+// GetLeaderboard.
+func (h *Handler) GetLeaderboardPosition(c *gin.Context) {
+	query, ok := parseLeaderboardQuery(c)
+	if !ok {
+		return
+	}
+
+	around := defaultAroundCount
+	if raw := c.Query("around"); raw != "" {
+		var err error
+		around, err = strconv.Atoi(raw)
+		if err != nil || around < 0 || around > maxAroundCount {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("around must be between 0 and %d", maxAroundCount)})
+			return
+		}
+	}
+
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	position, total, err := h.sessions.PlayerPosition(query, userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "You have no ranked sessions in this window"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch leaderboard"})
+		return
+	}
+
+	query.Offset = position - around
+	if query.Offset < 0 {
+		query.Offset = 0
+	}
+	query.Limit = position - query.Offset + around + 1
+
+	standings, err := h.sessions.PlayerLeaderboard(query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch leaderboard"})
+		return
+	}
+
+	// Percentile is the share of the other players ranked below the caller,
+	// so first place is 100 and last place is 0
+	percentile := 100.0
+	if total > 1 {
+		percentile = float64(total-position-1) / float64(total-1) * 100
+	}
+
+	c.JSON(http.StatusOK, models.LeaderboardPositionResponse{
+		Rank:       position + 1,
+		Total:      total,
+		Percentile: math.Round(percentile*10) / 10,
+		Entries:    playerEntries(standings, query.Offset, userID),
+	})
+}
+
+// GetPopularConfigurations lists the settings configurations with the most
+// players, each with the fingerprint to pass as ?settings= on the
+// leaderboards. It accepts the same window, tz and limit parameters.
+func (h *Handler) GetPopularConfigurations(c *gin.Context) {
+	query, ok := parseLeaderboardQuery(c)
+	if !ok {
+		return
+	}
+
+	stats, err := h.sessions.PopularConfigurations(query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch configurations"})
+		return
+	}
+
+	defaultFingerprint := generator.Fingerprint(getDefaultSettings())
+	entries := make([]models.ConfigurationEntry, 0, len(stats))
+	for _, stat := range stats {
+		settings, err := generator.ParseFingerprint(stat.Fingerprint)
+		if err != nil {
+			// Skip fingerprints written by an incompatible version
+			continue
+		}
+
+		entry := models.ConfigurationEntry{
+			Fingerprint: stat.Fingerprint,
+			IsDefault:   stat.Fingerprint == defaultFingerprint,
+			Sessions:    stat.Sessions,
+			Players:     stat.Players,
+		}
+		for _, op := range generator.EnabledOperations(settings) {
+			r := generator.OperandRanges(settings, op)
+			entry.Operations = append(entry.Operations, models.ConfigurationOperation{
+				Operation: string(op),
+				Min1:      r[0],
+				Max1:      r[1],
+				Min2:      r[2],
+				Max2:      r[3],
+			})
+		}
+		entries = append(entries, entry)
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
+
+// playerEntries converts standings starting at offset into leaderboard
+// entries, marking those belonging to the given user
+func playerEntries(standings []repository.PlayerStanding, offset int, userID uint) []models.PlayerLeaderboardEntry {
+	entries := make([]models.PlayerLeaderboardEntry, len(standings))
+	for i, standing := range standings {
+		entry := models.PlayerLeaderboardEntry{
+			Rank:      offset + i + 1,
+			BestScore: standing.Best.Score,
+			Duration:  standing.Best.Duration,
+			BestRunAt: standing.Best.StartedAt,
+			Runs:      standing.Runs,
+			IsYou:     userID != 0 && standing.Best.UserID != nil && *standing.Best.UserID == userID,
+		}
+		entry.Username, entry.IsAnonymous = displayName(standing.Best)
+		entries[i] = entry
+	}
+	return entries
+}
+
+// displayName returns the name shown for a session's player - either the
+// user's name or the anonymous name - and whether the player is anonymous
+func displayName(session models.Session) (name string, anonymous bool) {
+	if session.User != nil {
+		return session.User.Username, false
+	}
+
+	if session.AnonymousName != "" {
+		return session.AnonymousName, true
+	}
+	return fmt.Sprintf("Anonymous Player #%d", session.ID), true
+}
+
+// parseLeaderboardQuery reads the window, time zone, limit, settings,
+// duration, session_mode and target_count parameters. On failure the error
+// response is written and ok is false.
+func parseLeaderboardQuery(c *gin.Context) (query repository.LeaderboardQuery, ok bool) {
+	loc, ok := parseLocation(c)
+	if !ok {
+		return query, false
+	}
+
+	since, err := windowStart(c.DefaultQuery("window", "all"), time.Now(), loc)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return query, false
+	}
+
+	limit := defaultLeaderboardLimit
+	if raw := c.Query("limit"); raw != "" {
+		limit, err = strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > maxLeaderboardLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("limit must be between 1 and %d", maxLeaderboardLimit)})
+			return query, false
+		}
+	}
+
+	fingerprint := c.Query("settings")
+	if fingerprint != "" {
+		if _, err := generator.ParseFingerprint(fingerprint); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid settings fingerprint"})
+			return query, false
+		}
+	}
+
+	duration := defaultDuration
+	if raw := c.Query("duration"); raw != "" {
+		duration, err = strconv.Atoi(raw)
+		if err != nil || !validDuration(duration) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": durationError})
+			return query, false
+		}
+	}
+
+	query = repository.LeaderboardQuery{
+		Limit:       limit,
+		Since:       since,
+		Fingerprint: fingerprint,
+		Duration:    duration,
+		Mode:        models.ModeStandard,
+	}
+
+	switch c.DefaultQuery("session_mode", models.ModeStandard) {
+	case models.ModeStandard:
+	case models.ModeSprint:
+		// Sprints are ranked by problem count and time, not session length
+		query.Mode = models.ModeSprint
+		query.Duration = 0
+		query.TargetCount = defaultSprintCount
+		if raw := c.Query("target_count"); raw != "" {
+			query.TargetCount, err = strconv.Atoi(raw)
+			if err != nil || !validSprintCount(query.TargetCount) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": sprintCountError})
+				return query, false
+			}
+		}
+	case models.ModeSuddenDeath, models.ModePenalty:
+		query.Mode = c.Query("session_mode")
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "session_mode must be standard, sprint, sudden_death or penalty"})
+		return query, false
+	}
+
+	return query, true
+}
+
+// parseLocation reads the ?tz= time zone, defaulting to UTC. On failure the
+// error response is written and ok is false.
+func parseLocation(c *gin.Context) (*time.Location, bool) {
+	tz := c.Query("tz")
+	if tz == "" {
+		return time.UTC, true
+	}
+
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid time zone"})
+		return nil, false
+	}
+	return loc, true
+}
+
+// windowStart returns when a leaderboard window began in the given location,
+// or nil for the all-time window. Weeks start on Monday.
+func windowStart(window string, now time.Time, loc *time.Location) (*time.Time, error) {
+	now = now.In(loc)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+
+	var start time.Time
+	switch window {
+	case "all":
+		return nil, nil
+	case "day":
+		start = today
+	case "week":
+		// time.Weekday counts from Sunday; shift so Monday is day 0
+		daysSinceMonday := (int(now.Weekday()) + 6) % 7
+		start = today.AddDate(0, 0, -daysSinceMonday)
+	case "month":
+		start = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, loc)
+	default:
+		return nil, fmt.Errorf("window must be one of day, week, month or all")
+	}
+	return &start, nil
+}
@@ -0,0 +1,276 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+	_ "time/tzdata" // for the time zones windows are tested in
+
+	"github.com/calebwoo/mental-math-trainer/internal/models"
+)
+
+// seedSession stores an ended default-settings session, changed by mutate
+// if it isn't nil, and returns its ID
+func (a *testAPI) seedSession(userID *uint, anonymousName string, score int, startedAt time.Time, mutate func(*models.Session)) uint {
+	ended := startedAt.Add(2 * time.Minute)
+	session := models.Session{
+		UserID:              userID,
+		AnonymousName:       anonymousName,
+		Score:               score,
+		Duration:            120,
+		Mode:                models.ModeStandard,
+		IsDefaultSettings:   true,
+		LeaderboardEligible: true,
+		StartedAt:           startedAt,
+		EndedAt:             &ended,
+	}
+	if mutate != nil {
+		mutate(&session)
+	}
+	if err := a.repos.Sessions.Create(&session); err != nil {
+		a.t.Fatalf("create session: %v", err)
+	}
+	return session.ID
+}
+
+func TestLeaderboardOrdering(t *testing.T) {
+	a := newTestAPI(t)
+	aliceID, _ := a.user("alice")
+	bobID, _ := a.user("bob")
+
+	start := time.Now().Add(-24 * time.Hour).Truncate(time.Second)
+	a.seedSession(&aliceID, "", 30, start, nil)
+	a.seedSession(&bobID, "", 40, start.Add(time.Hour), nil)
+	a.seedSession(nil, "Swift Ace 1", 30, start.Add(-time.Hour), nil) // ties with alice but played first
+	a.seedSession(&aliceID, "", 35, start.Add(2*time.Hour), nil)
+	a.seedSession(&bobID, "", 99, start, func(s *models.Session) { s.LeaderboardEligible = false })
+	a.seedSession(&bobID, "", 98, start, func(s *models.Session) { s.IsDefaultSettings = false })
+
+	var entries []models.LeaderboardEntry
+	if status := a.do(http.MethodGet, "/api/leaderboard", nil, nil, &entries); status != http.StatusOK {
+		t.Fatalf("leaderboard: status %d", status)
+	}
+	want := []struct {
+		name  string
+		score int
+	}{{"bob", 40}, {"alice", 35}, {"Swift Ace 1", 30}, {"alice", 30}}
+	if len(entries) != len(want) {
+		t.Fatalf("leaderboard has %d entries, want %d: %+v", len(entries), len(want), entries)
+	}
+	for i, w := range want {
+		if entries[i].Rank != i+1 || entries[i].Username != w.name || entries[i].Score != w.score {
+			t.Errorf("entry %d = #%d %s %d, want #%d %s %d",
+				i, entries[i].Rank, entries[i].Username, entries[i].Score, i+1, w.name, w.score)
+		}
+	}
+
+	var players []models.PlayerLeaderboardEntry
+	if status := a.do(http.MethodGet, "/api/leaderboard?view=players", nil, nil, &players); status != http.StatusOK {
+		t.Fatalf("players leaderboard: status %d", status)
+	}
+	wantPlayers := []struct {
+		name  string
+		score int
+		runs  int
+	}{{"bob", 40, 1}, {"alice", 35, 2}, {"Swift Ace 1", 30, 1}}
+	if len(players) != len(wantPlayers) {
+		t.Fatalf("players leaderboard has %d entries, want %d: %+v", len(players), len(wantPlayers), players)
+	}
+	for i, w := range wantPlayers {
+		if players[i].Rank != i+1 || players[i].Username != w.name || players[i].BestScore != w.score || players[i].Runs != w.runs {
+			t.Errorf("player %d = #%d %s %d (%d runs), want #%d %s %d (%d runs)",
+				i, players[i].Rank, players[i].Username, players[i].BestScore, players[i].Runs, i+1, w.name, w.score, w.runs)
+		}
+	}
+
+	if status := a.do(http.MethodGet, "/api/leaderboard?view=teams", nil, nil, nil); status != http.StatusBadRequest {
+		t.Errorf("unknown view: status %d, want %d", status, http.StatusBadRequest)
+	}
+}
+
+func TestLeaderboardPosition(t *testing.T) {
+	a := newTestAPI(t)
+	aliceID, alice := a.user("alice")
+	bobID, bob := a.user("bob")
+	carolID, carol := a.user("carol")
+	_, dave := a.user("dave")
+
+	start := time.Now().Add(-24 * time.Hour).Truncate(time.Second)
+	a.seedSession(&aliceID, "", 30, start, nil)
+	a.seedSession(&aliceID, "", 35, start.Add(time.Hour), nil)
+	a.seedSession(&bobID, "", 40, start, nil)
+	a.seedSession(nil, "Swift Ace 1", 30, start.Add(-time.Hour), nil)
+	a.seedSession(&carolID, "", 30, start.Add(time.Hour), nil)
+
+	tests := []struct {
+		name       string
+		token      string
+		around     string
+		rank       int
+		percentile float64
+		entries    []string
+	}{
+		{"first", bob, "1", 1, 100, []string{"bob", "alice"}},
+		{"middle", alice, "1", 2, 66.7, []string{"bob", "alice", "Swift Ace 1"}},
+		{"last", carol, "5", 4, 0, []string{"bob", "alice", "Swift Ace 1", "carol"}},
+		{"alone", alice, "0", 2, 66.7, []string{"alice"}},
+	}
+	for _, tt := range tests {
+		var position models.LeaderboardPositionResponse
+		path := "/api/leaderboard/me?around=" + tt.around
+		if status := a.do(http.MethodGet, path, nil, bearer(tt.token), &position); status != http.StatusOK {
+			t.Fatalf("%s: status %d", tt.name, status)
+		}
+		if position.Rank != tt.rank || position.Total != 4 || position.Percentile != tt.percentile {
+			t.Errorf("%s: rank %d of %d at %v%%, want %d of 4 at %v%%",
+				tt.name, position.Rank, position.Total, position.Percentile, tt.rank, tt.percentile)
+		}
+		var names []string
+		for _, entry := range position.Entries {
+			names = append(names, entry.Username)
+			if entry.IsYou != (entry.Rank == tt.rank) {
+				t.Errorf("%s: %s has is_you %v", tt.name, entry.Username, entry.IsYou)
+			}
+		}
+		if !reflect.DeepEqual(names, tt.entries) {
+			t.Errorf("%s: entries %v, want %v", tt.name, names, tt.entries)
+		}
+	}
+
+	if status := a.do(http.MethodGet, "/api/leaderboard/me", nil, bearer(dave), nil); status != http.StatusNotFound {
+		t.Errorf("unranked player: status %d, want %d", status, http.StatusNotFound)
+	}
+	if status := a.do(http.MethodGet, "/api/leaderboard/me?around=51", nil, bearer(alice), nil); status != http.StatusBadRequest {
+		t.Errorf("around=51: status %d, want %d", status, http.StatusBadRequest)
+	}
+	if status := a.do(http.MethodGet, "/api/leaderboard/me", nil, nil, nil); status != http.StatusUnauthorized {
+		t.Errorf("no token: status %d, want %d", status, http.StatusUnauthorized)
+	}
+}
+
+func TestSprintLeaderboard(t *testing.T) {
+	a := newTestAPI(t)
+	aliceID, _ := a.user("alice")
+	bobID, _ := a.user("bob")
+
+	start := time.Now().Add(-24 * time.Hour).Truncate(time.Second)
+	sprint := func(targetCount int) func(*models.Session) {
+		return func(s *models.Session) {
+			s.Mode = models.ModeSprint
+			s.TargetCount = targetCount
+		}
+	}
+	a.seedSession(&aliceID, "", 45000, start, sprint(20))
+	a.seedSession(&bobID, "", 30000, start, sprint(20))
+	a.seedSession(&aliceID, "", 20000, start, sprint(10))
+	a.seedSession(&bobID, "", 40, start, nil)
+
+	tests := []struct {
+		path   string
+		scores []int
+	}{
+		{"/api/leaderboard?session_mode=sprint", []int{30000, 45000}},
+		{"/api/leaderboard?session_mode=sprint&target_count=10", []int{20000}},
+		{"/api/leaderboard", []int{40}},
+	}
+	for _, tt := range tests {
+		var entries []models.LeaderboardEntry
+		if status := a.do(http.MethodGet, tt.path, nil, nil, &entries); status != http.StatusOK {
+			t.Fatalf("%s: status %d", tt.path, status)
+		}
+		var scores []int
+		for _, entry := range entries {
+			scores = append(scores, entry.Score)
+		}
+		if !reflect.DeepEqual(scores, tt.scores) {
+			t.Errorf("%s: scores %v, want %v", tt.path, scores, tt.scores)
+		}
+	}
+
+	for _, path := range []string{
+		"/api/leaderboard?session_mode=marathon",
+		"/api/leaderboard?session_mode=sprint&target_count=15",
+	} {
+		if status := a.do(http.MethodGet, path, nil, nil, nil); status != http.StatusBadRequest {
+			t.Errorf("%s: status %d, want %d", path, status, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestWindowStart(t *testing.T) {
+	newYork, _ := time.LoadLocation("America/New_York")
+	tokyo, _ := time.LoadLocation("Asia/Tokyo")
+	wednesday := time.Date(2024, 3, 6, 2, 30, 0, 0, time.UTC)
+	sunday := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		window string
+		now    time.Time
+		loc    *time.Location
+		want   time.Time
+	}{
+		{"day", wednesday, time.UTC, time.Date(2024, 3, 6, 0, 0, 0, 0, time.UTC)},
+		{"week", wednesday, time.UTC, time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)},
+		{"week", sunday, time.UTC, time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)},
+		{"month", wednesday, time.UTC, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)},
+		// Still Tuesday evening in New York, and already Wednesday in Tokyo
+		{"day", wednesday, newYork, time.Date(2024, 3, 5, 5, 0, 0, 0, time.UTC)},
+		{"week", wednesday, newYork, time.Date(2024, 3, 4, 5, 0, 0, 0, time.UTC)},
+		{"month", wednesday, newYork, time.Date(2024, 3, 1, 5, 0, 0, 0, time.UTC)},
+		{"day", wednesday, tokyo, time.Date(2024, 3, 5, 15, 0, 0, 0, time.UTC)},
+		{"week", wednesday, tokyo, time.Date(2024, 3, 3, 15, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		start, err := windowStart(tt.window, tt.now, tt.loc)
+		if err != nil || start == nil || !start.Equal(tt.want) {
+			t.Errorf("windowStart(%q, %v, %v) = %v, %v; want %v", tt.window, tt.now, tt.loc, start, err, tt.want)
+		}
+	}
+
+	if start, err := windowStart("all", wednesday, time.UTC); start != nil || err != nil {
+		t.Errorf("all-time window = %v, %v; want no start", start, err)
+	}
+	if _, err := windowStart("year", wednesday, time.UTC); err == nil {
+		t.Error("unknown window accepted")
+	}
+}
+
+func TestLeaderboardWindowAndLimit(t *testing.T) {
+	a := newTestAPI(t)
+	aliceID, _ := a.user("alice")
+	bobID, _ := a.user("bob")
+
+	a.seedSession(&aliceID, "", 30, time.Now(), nil)
+	a.seedSession(&bobID, "", 40, time.Now().AddDate(0, 0, -40), nil)
+
+	for query, want := range map[string]int{
+		"":                     2,
+		"?window=all":          2,
+		"?window=month":        1,
+		"?window=day&tz=UTC":   1,
+		"?window=all&limit=1":  1,
+		"?window=week&limit=5": 1,
+	} {
+		var entries []models.LeaderboardEntry
+		if status := a.do(http.MethodGet, "/api/leaderboard"+query, nil, nil, &entries); status != http.StatusOK {
+			t.Errorf("leaderboard%s: status %d", query, status)
+			continue
+		}
+		if len(entries) != want {
+			t.Errorf("leaderboard%s has %d entries, want %d", query, len(entries), want)
+		}
+	}
+
+	for _, query := range []string{
+		"?window=year",
+		"?tz=Mars/Olympus",
+		"?limit=0",
+		fmt.Sprintf("?limit=%d", maxLeaderboardLimit+1),
+	} {
+		if status := a.do(http.MethodGet, "/api/leaderboard"+query, nil, nil, nil); status != http.StatusBadRequest {
+			t.Errorf("leaderboard%s: status %d, want 400", query, status)
+		}
+	}
+}
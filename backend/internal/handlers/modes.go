@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/calebwoo/mental-math-trainer/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// defaultSprintCount is the number of problems in a sprint when none is requested
+const defaultSprintCount = 20
+
+// sprintCounts are the sprint lengths clients may choose. Leaderboards rank
+// each length separately.
+var sprintCounts = []int{10, 20, 30, 50}
+
+// sprintCountError is returned for a sprint length not in sprintCounts
+const sprintCountError = "target_count must be one of 10, 20, 30 or 50"
+
+// validSprintCount reports whether a sprint length is one clients may choose
+func validSprintCount(count int) bool {
+	for _, c := range sprintCounts {
+		if count == c {
+			return true
+		}
+	}
+	return false
+}
+
+// penaltySeconds is the time a wrong answer takes off a penalty session
+const penaltySeconds = 5
+
+// serverScored reports whether a mode's score is the recorded correct
+// problems rather than the client's claim
+func serverScored(mode string) bool {
+	return mode == models.ModeSuddenDeath || mode == models.ModePenalty
+}
+
+// requireOpen checks that a session can still take problems. Ended sessions
+// take none, and a session whose time ran out more than the grace period
+// ago is ended at its deadline. On failure the error response is written
+// and ok is false.
+func (h *Handler) requireOpen(c *gin.Context, session *models.Session) bool {
+	if session.EndedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Session already completed"})
+		return false
+	}
+
+	deadline, limited := session.Deadline()
+	if limited && time.Now().After(deadline.Add(h.grace)) {
+		if err := h.endSession(session, deadline); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update session"})
+			return false
+		}
+		c.JSON(http.StatusConflict, gin.H{"error": "Session time is up"})
+		return false
+	}
+	return true
+}
+
+// endSession ends a session at the given time, scoring it by its recorded
+// correct problems
+func (h *Handler) endSession(session *models.Session, at time.Time) error {
+	correct, err := h.problems.CountCorrect(session.ID)
+	if err != nil {
+		return err
+	}
+	session.EndedAt = &at
+	session.Score = correct
+	return h.sessions.Update(session)
+}
+
+// afterAnswer applies the session's mode rules once one of its problems has
+// been answered, ending the session if the mode says it is over. It reports
+// whether the session ended.
+func (h *Handler) afterAnswer(session *models.Session, problem models.Problem) (bool, error) {
+	switch session.Mode {
+	case models.ModeSprint:
+		if !problem.IsCorrect {
+			return false, nil
+		}
+		correct, err := h.problems.CountCorrect(session.ID)
+		if err != nil || correct < session.TargetCount {
+			return false, err
+		}
+
+		// The sprint is scored by the time taken to reach the target
+		now := time.Now()
+		session.EndedAt = &now
+		session.Score = int(now.Sub(session.StartedAt).Milliseconds())
+		return true, h.sessions.Update(session)
+	case models.ModeSuddenDeath:
+		if problem.IsCorrect {
+			return false, nil
+		}
+		session.WrongAnswers++
+		return true, h.endSession(session, time.Now())
+	case models.ModePenalty:
+		if problem.IsCorrect {
+			return false, nil
+		}
+		session.WrongAnswers++
+		session.PenaltySeconds += penaltySeconds
+
+		// The penalty may use up the rest of the session's time
+		now := time.Now()
+		if deadline, _ := session.Deadline(); !now.Before(deadline) {
+			return true, h.endSession(session, deadline)
+		}
+		return false, h.sessions.Update(session)
+	default:
+		return false, nil
+	}
+}
@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/calebwoo/mental-math-trainer/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// createModeSession creates a session in the given mode as the caller
+func (a *testAPI) createModeSession(headers map[string]string, body gin.H) uint {
+	var created models.CreateSessionResponse
+	if status := a.do(http.MethodPost, "/api/sessions", body, headers, &created); status != http.StatusCreated {
+		a.t.Fatalf("create %v session: status %d", body, status)
+	}
+	return created.SessionID
+}
+
+// session loads a session straight from the repository
+func (a *testAPI) session(id uint) *models.Session {
+	session, err := a.repos.Sessions.FindByID(id)
+	if err != nil {
+		a.t.Fatalf("find session: %v", err)
+	}
+	return session
+}
+
+func TestSprintEndsAtItsTarget(t *testing.T) {
+	a := newTestAPI(t)
+	_, alice := a.user("alice")
+
+	if status := a.do(http.MethodPost, "/api/sessions", gin.H{"mode": models.ModeSprint, "target_count": 15}, bearer(alice), nil); status != http.StatusBadRequest {
+		t.Errorf("sprint of 15: status %d, want 400", status)
+	}
+
+	id := a.createModeSession(bearer(alice), gin.H{"mode": models.ModeSprint, "target_count": 10})
+	start := a.startedAgo(id, time.Minute).StartedAt
+
+	// A wrong answer doesn't count towards the target
+	if _, verdict := a.answerNext(id, bearer(alice), true); verdict.SessionEnded {
+		t.Fatal("wrong answer ended the sprint")
+	}
+	for i := 1; i <= 10; i++ {
+		_, verdict := a.answerNext(id, bearer(alice), false)
+		if verdict.SessionEnded != (i == 10) {
+			t.Fatalf("correct answer %d: session_ended = %v", i, verdict.SessionEnded)
+		}
+	}
+
+	// The sprint is scored by the milliseconds it took
+	session := a.session(id)
+	if session.EndedAt == nil {
+		t.Fatal("finished sprint has no end")
+	}
+	if want := int(session.EndedAt.Sub(start).Milliseconds()); session.Score != want || session.Score < int(time.Minute.Milliseconds()) {
+		t.Errorf("sprint score = %d, want the %d ms it took", session.Score, want)
+	}
+	if !session.LeaderboardEligible {
+		t.Errorf("finished sprint is off the leaderboard: %q", session.FlagReason)
+	}
+	if status := a.do(http.MethodPost, fmt.Sprintf("/api/sessions/%d/next", id), nil, bearer(alice), nil); status != http.StatusConflict {
+		t.Errorf("next after the sprint: status %d, want 409", status)
+	}
+
+	// Completing a sprint before its target gives up without a time
+	early := a.createModeSession(bearer(alice), gin.H{"mode": models.ModeSprint, "target_count": 10})
+	a.answerNext(early, bearer(alice), false)
+	var completed models.Session
+	if status := a.do(http.MethodPatch, fmt.Sprintf("/api/sessions/%d/complete", early), nil, bearer(alice), &completed); status != http.StatusOK {
+		t.Fatalf("complete early: status %d", status)
+	}
+	if completed.Score != 0 || completed.LeaderboardEligible {
+		t.Errorf("abandoned sprint scored %d, eligible %v; want 0 and off the leaderboard", completed.Score, completed.LeaderboardEligible)
+	}
+}
+
+func TestSuddenDeathEndsAtFirstMistake(t *testing.T) {
+	a := newTestAPI(t)
+	_, alice := a.user("alice")
+	id := a.createModeSession(bearer(alice), gin.H{"mode": models.ModeSuddenDeath})
+
+	for i := 0; i < 3; i++ {
+		if _, verdict := a.answerNext(id, bearer(alice), false); verdict.SessionEnded {
+			t.Fatalf("correct answer %d ended the session", i+1)
+		}
+	}
+	if _, verdict := a.answerNext(id, bearer(alice), true); !verdict.SessionEnded || verdict.Score != 3 {
+		t.Fatalf("wrong answer verdict = %+v, want the session ended with score 3", verdict)
+	}
+
+	session := a.session(id)
+	if session.EndedAt == nil || session.Score != 3 || session.WrongAnswers != 1 {
+		t.Errorf("session ended at %v with score %d and %d wrong, want an end, 3 and 1",
+			session.EndedAt, session.Score, session.WrongAnswers)
+	}
+	if status := a.do(http.MethodPost, fmt.Sprintf("/api/sessions/%d/next", id), nil, bearer(alice), nil); status != http.StatusConflict {
+		t.Errorf("next after the mistake: status %d, want 409", status)
+	}
+	if status := a.do(http.MethodPatch, fmt.Sprintf("/api/sessions/%d/complete", id), nil, bearer(alice), nil); status != http.StatusConflict {
+		t.Errorf("complete after the mistake: status %d, want 409", status)
+	}
+}
+
+func TestPenaltyShortensTheSession(t *testing.T) {
+	a := newTestAPI(t)
+	_, alice := a.user("alice")
+	id := a.createModeSession(bearer(alice), gin.H{"mode": models.ModePenalty, "duration": 30})
+
+	a.answerNext(id, bearer(alice), false)
+	if _, verdict := a.answerNext(id, bearer(alice), true); verdict.SessionEnded {
+		t.Fatal("first wrong answer ended the session with time left")
+	}
+	if session := a.session(id); session.PenaltySeconds != penaltySeconds || session.WrongAnswers != 1 {
+		t.Errorf("penalty = %ds after %d wrong, want %ds after 1", session.PenaltySeconds, session.WrongAnswers, penaltySeconds)
+	}
+
+	// With 3 seconds left, another penalty uses up the rest of the time and
+	// the session ends at its shortened deadline
+	start := a.startedAgo(id, 22*time.Second).StartedAt
+	if _, verdict := a.answerNext(id, bearer(alice), true); !verdict.SessionEnded {
+		t.Fatal("penalty past the deadline didn't end the session")
+	}
+	session := a.session(id)
+	deadline := start.Add(time.Duration(30-2*penaltySeconds) * time.Second)
+	if session.EndedAt == nil || !session.EndedAt.Equal(deadline) || session.Score != 1 {
+		t.Errorf("session ended at %v with score %d, want %v and 1", session.EndedAt, session.Score, deadline)
+	}
+}
+
+func TestPenaltyIsScoredByTheServer(t *testing.T) {
+	a := newTestAPI(t)
+	_, alice := a.user("alice")
+	id := a.createModeSession(bearer(alice), gin.H{"mode": models.ModePenalty})
+
+	a.answerNext(id, bearer(alice), false)
+	a.answerNext(id, bearer(alice), true)
+	var completed models.Session
+	if status := a.do(http.MethodPatch, fmt.Sprintf("/api/sessions/%d/complete", id), gin.H{"score": 40}, bearer(alice), &completed); status != http.StatusOK {
+		t.Fatalf("complete: status %d", status)
+	}
+	if completed.Score != 1 || !completed.LeaderboardEligible {
+		t.Errorf("completed with score %d, eligible %v; want the 1 recorded and eligible", completed.Score, completed.LeaderboardEligible)
+	}
+}
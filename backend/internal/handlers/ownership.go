@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"net/http"
+
+	"github.com/calebwoo/mental-math-trainer/internal/models"
+	"github.com/calebwoo/mental-math-trainer/internal/repository"
+	"github.com/gin-gonic/gin"
+)
+
+// sessionSecretHeader carries the secret returned when an anonymous session is created
+const sessionSecretHeader = "X-Session-Secret"
+
+// findOwnedSession loads the session named by the :id route parameter and
+// checks that the caller owns it. Sessions of registered users require that
+// user's token; anonymous sessions require their session secret or the
+// anonymous token of their creator. On failure the error response is written
+// and ok is false.
+func (h *Handler) findOwnedSession(c *gin.Context) (session *models.Session, ok bool) {
+	sessionID, valid := parseID(c.Param("id"))
+	if !valid {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session ID"})
+		return nil, false
+	}
+
+	session, err := h.sessions.FindByID(sessionID)
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch session"})
+		return nil, false
+	}
+
+	if !ownsSession(c, session) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have access to this session"})
+		return nil, false
+	}
+
+	return session, true
+}
+
+// ownsSession reports whether the caller may access the session
+func ownsSession(c *gin.Context, session *models.Session) bool {
+	if session.UserID != nil {
+		userID, exists := c.Get("user_id")
+		return exists && userID.(uint) == *session.UserID
+	}
+
+	// The anonymous player who created the session may always access it
+	if anonymousID, exists := c.Get("anonymous_id"); exists && session.AnonymousID != "" {
+		if anonymousID.(string) == session.AnonymousID {
+			return true
+		}
+	}
+
+	return sessionSecretMatches(session, c.GetHeader(sessionSecretHeader))
+}
+
+// sessionSecretMatches reports whether secret is the anonymous session's secret
+func sessionSecretMatches(session *models.Session, secret string) bool {
+	if secret == "" || session.SecretHash == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(hashSessionSecret(secret)), []byte(session.SecretHash)) == 1
+}
+
+// generateSessionSecret returns a random secret for an anonymous session
+func generateSessionSecret() (string, error) {
+	return randomHex(32)
+}
+
+// generateAnonymousID returns a random identity for an anonymous player
+func generateAnonymousID() (string, error) {
+	return randomHex(16)
+}
+
+// randomHex returns n random bytes, hex encoded
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// hashSessionSecret hashes a session secret for storage
+func hashSessionSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
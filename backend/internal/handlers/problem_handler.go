@@ -1,43 +1,79 @@
 package handlers
 
 import (
-	"fmt"
+	"errors"
+	"log"
+	"math/rand"
 	"net/http"
+	"time"
 
-	"github.com/calebwoo/mental-math-trainer/internal/database"
+	"github.com/calebwoo/mental-math-trainer/internal/generator"
 	"github.com/calebwoo/mental-math-trainer/internal/models"
+	"github.com/calebwoo/mental-math-trainer/internal/practice"
+	"github.com/calebwoo/mental-math-trainer/internal/repository"
 	"github.com/gin-gonic/gin"
 )
 
 // SubmitProblem records a problem attempt for a session
-func SubmitProblem(c *gin.Context) {
-	sessionID := c.Param("id")
-
+func (h *Handler) SubmitProblem(c *gin.Context) {
 	var req models.SubmitProblemRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Verify session exists
-	var session models.Session
-	if err := database.DB.First(&session, sessionID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+	// Verify session exists and belongs to the caller
+	session, ok := h.findOwnedSession(c)
+	if !ok {
+		return
+	}
+
+	// Server-issued sessions only accept answers to problems the server generated
+	if session.ServerIssued {
+		c.JSON(http.StatusConflict, gin.H{"error": "Session uses server-issued problems"})
+		return
+	}
+	// Problems may be submitted in a batch before completing, but only
+	// while the session's time lasts
+	if !h.requireOpen(c, session) {
 		return
 	}
 
-	// Convert sessionID string to uint
-	var sessionIDUint uint
-	if _, err := fmt.Sscanf(sessionID, "%d", &sessionIDUint); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session ID"})
+	operation, operands, err := problemStructure(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	isCorrect := req.UserAnswer == req.Answer
+	// The client generated the problem, so check it is one the session's
+	// settings could have asked and mark the answer against the real answer
+	answer, ok := generator.Answer(operation, operands)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "question must be a problem such as \"12 × 7\" with a whole-number answer"})
+		return
+	}
+	if req.Answer != answer {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "answer doesn't match the question"})
+		return
+	}
+	settings, err := h.playedSettings(session)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load session settings"})
+		return
+	}
+	if err == nil && !generator.Fits(settings, operation, operands) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "problem is outside the session's settings"})
+		return
+	}
+
+	isCorrect := req.UserAnswer == answer
 
 	problem := models.Problem{
-		SessionID:   sessionIDUint,
+		SessionID:   session.ID,
 		Question:    req.Question,
+		Operation:   string(operation),
+		Operands:    operands,
+		Difficulty:  generator.Difficulty(operands),
 		Answer:      req.Answer,
 		UserAnswer:  &req.UserAnswer,
 		TimeSpentMs: req.TimeSpentMs,
@@ -45,10 +81,249 @@ func SubmitProblem(c *gin.Context) {
 		IsCorrect:   isCorrect,
 	}
 
-	if err := database.DB.Create(&problem).Error; err != nil {
+	if err := h.problems.Create(&problem); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save problem"})
 		return
 	}
+	h.recordFact(session.UserID, problem)
+
+	if _, err := h.afterAnswer(session, problem); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update session"})
+		return
+	}
 
 	c.JSON(http.StatusCreated, problem)
 }
+
+// problemStructure returns the operation and operands of a submitted problem,
+// read from the question unless the client sent them. Sent values must be
+// complete and agree with the question when it can be read. Both are empty
+// for questions that can't be read.
+func problemStructure(req models.SubmitProblemRequest) (generator.Operation, []int, error) {
+	parsedOp, parsedOperands, parsed := generator.ParseQuestion(req.Question)
+	if req.Operation == "" && len(req.Operands) == 0 {
+		return parsedOp, parsedOperands, nil
+	}
+
+	op, ok := generator.ParseOperation(req.Operation)
+	if !ok || len(req.Operands) != 2 {
+		return "", nil, errors.New("operation must be addition, subtraction, multiplication or division, with two operands")
+	}
+	if parsed && (op != parsedOp || req.Operands[0] != parsedOperands[0] || req.Operands[1] != parsedOperands[1]) {
+		return "", nil, errors.New("operation and operands don't match the question")
+	}
+	return op, req.Operands, nil
+}
+
+// NextProblem issues the next problem for a server-issued session.
+// An unanswered problem is returned again rather than replaced, so
+// clients can't reroll for an easier question.
+func (h *Handler) NextProblem(c *gin.Context) {
+	session, ok := h.findOwnedSession(c)
+	if !ok {
+		return
+	}
+
+	if !session.ServerIssued {
+		c.JSON(http.StatusConflict, gin.H{"error": "Session does not use server-issued problems"})
+		return
+	}
+	if !h.requireOpen(c, session) {
+		return
+	}
+
+	// Return the pending problem if there is one
+	pending, err := h.problems.FindPending(session.ID)
+	if err == nil {
+		c.JSON(http.StatusOK, issuedProblem(*pending))
+		return
+	}
+	if !errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch problem"})
+		return
+	}
+
+	generated, err := h.generateProblem(session)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate problem"})
+		return
+	}
+
+	problem := models.Problem{
+		SessionID:  session.ID,
+		Question:   generated.Question,
+		Operation:  string(generated.Operation),
+		Operands:   generated.Operands,
+		Difficulty: generator.Difficulty(generated.Operands),
+		Answer:     generated.Answer,
+	}
+
+	if err := h.problems.Create(&problem); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save problem"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, issuedProblem(problem))
+}
+
+// AnswerProblem checks the answer to a server-issued problem
+func (h *Handler) AnswerProblem(c *gin.Context) {
+	problemID, valid := parseID(c.Param("problemId"))
+	if !valid {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid problem ID"})
+		return
+	}
+
+	var req models.AnswerProblemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	session, ok := h.findOwnedSession(c)
+	if !ok {
+		return
+	}
+
+	if !session.ServerIssued {
+		c.JSON(http.StatusConflict, gin.H{"error": "Session does not use server-issued problems"})
+		return
+	}
+	if !h.requireOpen(c, session) {
+		return
+	}
+
+	problem, err := h.problems.FindInSession(session.ID, problemID)
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Problem not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch problem"})
+		return
+	}
+
+	if problem.UserAnswer != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Problem already answered"})
+		return
+	}
+
+	problem.UserAnswer = req.UserAnswer
+	problem.TimeSpentMs = req.TimeSpentMs
+	problem.TypoCount = req.TypoCount
+	problem.IsCorrect = *req.UserAnswer == problem.Answer
+
+	if err := h.problems.Update(problem); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save problem"})
+		return
+	}
+	h.recordFact(session.UserID, *problem)
+
+	ended, err := h.afterAnswer(session, *problem)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update session"})
+		return
+	}
+
+	score, err := h.problems.CountCorrect(session.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to score session"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.AnswerProblemResponse{
+		ProblemID:    problem.ID,
+		IsCorrect:    problem.IsCorrect,
+		Answer:       problem.Answer,
+		Score:        score,
+		SessionEnded: ended,
+	})
+}
+
+// generateProblem picks the next problem of a server-issued session. Weak
+// facts sessions favor the facts the user struggles with; others draw
+// uniformly from the settings the session was created with.
+func (h *Handler) generateProblem(session *models.Session) (generator.Problem, error) {
+	settings, err := h.playedSettings(session)
+	if err != nil {
+		return generator.Problem{}, err
+	}
+	gen := generator.New(settings)
+	if session.Mode != models.ModeWeakFacts || session.UserID == nil {
+		return gen.Generate(), nil
+	}
+
+	var operations []string
+	for _, op := range generator.EnabledOperations(settings) {
+		operations = append(operations, string(op))
+	}
+	schedules, err := h.facts.ListByUser(*session.UserID, operations)
+	if err != nil {
+		return generator.Problem{}, err
+	}
+
+	// Facts scheduled while the user had other settings may fall outside
+	// this session's ranges
+	var fitting []models.FactSchedule
+	for _, schedule := range schedules {
+		if generator.FactFits(settings, scheduledFact(schedule)) {
+			fitting = append(fitting, schedule)
+		}
+	}
+
+	now := time.Now()
+	schedule, ok := practice.Pick(fitting, now, rand.New(rand.NewSource(now.UnixNano())))
+	if !ok {
+		return gen.Generate(), nil
+	}
+	return gen.GenerateFact(scheduledFact(schedule)), nil
+}
+
+// scheduledFact returns the fact a schedule is for
+func scheduledFact(schedule models.FactSchedule) generator.Fact {
+	return generator.Fact{
+		Operation: generator.Operation(schedule.Operation),
+		A:         schedule.A,
+		B:         schedule.B,
+	}
+}
+
+// recordFact updates a signed-in user's schedule for the fact a problem
+// drills. It is best effort: a failure is logged and the answer still counts.
+func (h *Handler) recordFact(userID *uint, problem models.Problem) {
+	if userID == nil || problem.UserAnswer == nil {
+		return
+	}
+	fact, ok := generator.FactOf(generator.Operation(problem.Operation), problem.Operands, problem.Answer)
+	if !ok {
+		return
+	}
+
+	schedule, err := h.facts.Find(*userID, string(fact.Operation), fact.A, fact.B)
+	if errors.Is(err, repository.ErrNotFound) {
+		schedule = &models.FactSchedule{
+			UserID:    *userID,
+			Operation: string(fact.Operation),
+			A:         fact.A,
+			B:         fact.B,
+		}
+	} else if err != nil {
+		log.Printf("Failed to load schedule for user %d: %v", *userID, err)
+		return
+	}
+
+	practice.Review(schedule, problem.IsCorrect, problem.TimeSpentMs, time.Now())
+	if err := h.facts.Save(schedule); err != nil {
+		log.Printf("Failed to save schedule for user %d: %v", *userID, err)
+	}
+}
+
+// issuedProblem converts a stored problem to its public form, hiding the answer
+func issuedProblem(problem models.Problem) models.IssuedProblem {
+	return models.IssuedProblem{
+		ID:        problem.ID,
+		SessionID: problem.SessionID,
+		Question:  problem.Question,
+		IssuedAt:  problem.CreatedAt,
+	}
+}
@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/calebwoo/mental-math-trainer/internal/generator"
+	"github.com/calebwoo/mental-math-trainer/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+func TestSubmitProblemChecksAnswer(t *testing.T) {
+	a := newTestAPI(t)
+	_, alice := a.user("alice")
+	created := a.createSession(bearer(alice))
+	path := fmt.Sprintf("/api/sessions/%d/problems", created.SessionID)
+
+	for name, body := range map[string]gin.H{
+		"wrong answer":      {"question": "3 × 4", "answer": 7, "user_answer": 7, "time_spent_ms": 1000},
+		"outside settings":  {"question": "1 + 1", "answer": 2, "user_answer": 2, "time_spent_ms": 1000},
+		"unreadable":        {"question": "three times four", "answer": 12, "user_answer": 12, "time_spent_ms": 1000},
+		"inexact division":  {"question": "10 ÷ 3", "answer": 3, "user_answer": 3, "time_spent_ms": 1000},
+		"mismatched fields": {"question": "3 × 4", "operation": "addition", "operands": []int{3, 4}, "answer": 12, "user_answer": 12, "time_spent_ms": 1000},
+	} {
+		if status := a.do(http.MethodPost, path, body, bearer(alice), nil); status != http.StatusBadRequest {
+			t.Errorf("%s: status %d, want 400", name, status)
+		}
+	}
+
+	count, err := a.repos.Problems.CountCorrect(created.SessionID)
+	if err != nil || count != 0 {
+		t.Fatalf("correct problems = %d, %v; want 0", count, err)
+	}
+}
+
+func TestSubmitProblemMarksAgainstRealAnswer(t *testing.T) {
+	a := newTestAPI(t)
+	_, alice := a.user("alice")
+	created := a.createSession(bearer(alice))
+
+	if status := a.submit(created.SessionID, bearer(alice), 6, 7, 42); status != http.StatusCreated {
+		t.Fatalf("correct answer: status %d", status)
+	}
+	if status := a.submit(created.SessionID, bearer(alice), 6, 8, 42); status != http.StatusCreated {
+		t.Fatalf("wrong answer: status %d", status)
+	}
+	if count, err := a.repos.Problems.CountCorrect(created.SessionID); err != nil || count != 1 {
+		t.Fatalf("correct problems = %d, %v; want 1", count, err)
+	}
+}
+
+// answerNext fetches the next problem of a server-issued session and answers
+// it, correctly unless wrong is set. It returns the problem and the verdict.
+func (a *testAPI) answerNext(sessionID uint, headers map[string]string, wrong bool) (models.IssuedProblem, models.AnswerProblemResponse) {
+	var issued models.IssuedProblem
+	if status := a.do(http.MethodPost, fmt.Sprintf("/api/sessions/%d/next", sessionID), nil, headers, &issued); status != http.StatusCreated && status != http.StatusOK {
+		a.t.Fatalf("next problem: status %d", status)
+	}
+	op, operands, ok := generator.ParseQuestion(issued.Question)
+	if !ok {
+		a.t.Fatalf("issued question %q can't be read", issued.Question)
+	}
+	answer, _ := generator.Answer(op, operands)
+	if wrong {
+		answer++
+	}
+
+	var verdict models.AnswerProblemResponse
+	path := fmt.Sprintf("/api/sessions/%d/problems/%d/answer", sessionID, issued.ID)
+	if status := a.do(http.MethodPost, path, gin.H{"user_answer": answer, "time_spent_ms": 1000}, headers, &verdict); status != http.StatusOK {
+		a.t.Fatalf("answer %q: status %d", issued.Question, status)
+	}
+	return issued, verdict
+}
+
+func TestWeakFactsStayInSettings(t *testing.T) {
+	a := newTestAPI(t)
+	aliceID, alice := a.user("alice")
+
+	settings := models.Settings{
+		UserID: aliceID,
+		OperationSettings: models.OperationSettings{
+			MultiplicationEnabled: true,
+			MultiplicationMin1:    2,
+			MultiplicationMax1:    12,
+			MultiplicationMin2:    2,
+			MultiplicationMax2:    12,
+		},
+	}
+	if err := a.repos.Settings.Create(&settings); err != nil {
+		t.Fatalf("create settings: %v", err)
+	}
+
+	// A fact missed often under earlier, wider settings, and one that fits
+	due := time.Now().Add(-time.Hour)
+	for _, schedule := range []models.FactSchedule{
+		{UserID: aliceID, Operation: "multiplication", A: 7, B: 85, Attempts: 10, TotalTimeMs: 90000, Ease: 1.3, DueAt: due},
+		{UserID: aliceID, Operation: "multiplication", A: 6, B: 7, Attempts: 10, Correct: 5, TotalTimeMs: 30000, Ease: 2.5, DueAt: due},
+	} {
+		if err := a.repos.Facts.Save(&schedule); err != nil {
+			t.Fatalf("save schedule: %v", err)
+		}
+	}
+
+	var created models.CreateSessionResponse
+	if status := a.do(http.MethodPost, "/api/sessions", gin.H{"mode": models.ModeWeakFacts}, bearer(alice), &created); status != http.StatusCreated {
+		t.Fatalf("create session: status %d", status)
+	}
+	for i := 0; i < 40; i++ {
+		issued, _ := a.answerNext(created.SessionID, bearer(alice), false)
+		op, operands, _ := generator.ParseQuestion(issued.Question)
+		if !generator.Fits(settings, op, operands) {
+			t.Fatalf("problem %d, %q, is outside the session's settings", i+1, issued.Question)
+		}
+	}
+}
+
+func TestServerIssuedProblems(t *testing.T) {
+	a := newTestAPI(t)
+	_, alice := a.user("alice")
+
+	var created models.CreateSessionResponse
+	if status := a.do(http.MethodPost, "/api/sessions", gin.H{"server_issued": true}, bearer(alice), &created); status != http.StatusCreated {
+		t.Fatalf("create session: status %d", status)
+	}
+	next := fmt.Sprintf("/api/sessions/%d/next", created.SessionID)
+
+	// The answer stays on the server, and asking again returns the same
+	// problem rather than a new one
+	var first, again map[string]any
+	if status := a.do(http.MethodPost, next, nil, bearer(alice), &first); status != http.StatusCreated {
+		t.Fatalf("next problem: status %d, want 201", status)
+	}
+	if _, leaked := first["answer"]; leaked {
+		t.Errorf("issued problem includes its answer: %v", first)
+	}
+	if status := a.do(http.MethodPost, next, nil, bearer(alice), &again); status != http.StatusOK {
+		t.Fatalf("pending problem: status %d, want 200", status)
+	}
+	if again["id"] != first["id"] || again["question"] != first["question"] {
+		t.Errorf("pending problem = %v, want %v again", again, first)
+	}
+
+	issued, verdict := a.answerNext(created.SessionID, bearer(alice), false)
+	if !verdict.IsCorrect || verdict.Score != 1 || verdict.SessionEnded {
+		t.Errorf("correct answer verdict = %+v", verdict)
+	}
+	answer := fmt.Sprintf("/api/sessions/%d/problems/%d/answer", created.SessionID, issued.ID)
+	if status := a.do(http.MethodPost, answer, gin.H{"user_answer": verdict.Answer}, bearer(alice), nil); status != http.StatusConflict {
+		t.Errorf("second answer: status %d, want 409", status)
+	}
+
+	issued, verdict = a.answerNext(created.SessionID, bearer(alice), true)
+	op, operands, _ := generator.ParseQuestion(issued.Question)
+	if want, _ := generator.Answer(op, operands); verdict.IsCorrect || verdict.Answer != want || verdict.Score != 1 {
+		t.Errorf("wrong answer verdict = %+v, want answer %d and score 1", verdict, want)
+	}
+
+	// Client-generated problems and claimed scores are refused or ignored
+	if status := a.submit(created.SessionID, bearer(alice), 3, 4, 12); status != http.StatusConflict {
+		t.Errorf("client problem: status %d, want 409", status)
+	}
+	var completed models.Session
+	path := fmt.Sprintf("/api/sessions/%d/complete", created.SessionID)
+	if status := a.do(http.MethodPatch, path, gin.H{"score": 50}, bearer(alice), &completed); status != http.StatusOK {
+		t.Fatalf("complete: status %d", status)
+	}
+	if completed.Score != 1 {
+		t.Errorf("score = %d, want the 1 correct answer recorded", completed.Score)
+	}
+
+	// Sessions with client-generated problems have nothing to issue
+	client := a.createSession(bearer(alice))
+	if status := a.do(http.MethodPost, fmt.Sprintf("/api/sessions/%d/next", client.SessionID), nil, bearer(alice), nil); status != http.StatusConflict {
+		t.Errorf("next on a client session: status %d, want 409", status)
+	}
+}
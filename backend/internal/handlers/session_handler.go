@@ -1,30 +1,92 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"math/rand"
 	"net/http"
 	"strconv"
 	"time"
 
-	"github.com/calebwoo/mental-math-trainer/internal/database"
+	"github.com/calebwoo/mental-math-trainer/internal/generator"
 	"github.com/calebwoo/mental-math-trainer/internal/models"
+	"github.com/calebwoo/mental-math-trainer/internal/repository"
 	"github.com/gin-gonic/gin"
 )
 
+// defaultDuration is the length of a session in seconds when none is requested
+const defaultDuration = 120
+
+// sessionDurations are the session lengths in seconds clients may choose.
+// Leaderboards rank each length separately.
+var sessionDurations = []int{30, 60, 120, 300, 600}
+
+// durationError is returned for a session length not in sessionDurations
+const durationError = "duration must be one of 30, 60, 120, 300 or 600 seconds"
+
 // CreateSession creates a new practice session
-func CreateSession(c *gin.Context) {
+func (h *Handler) CreateSession(c *gin.Context) {
+	// An empty body asks for the defaults
 	var req models.CreateSessionRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		// If no body provided, use defaults
-		req.IsDefaultSettings = false
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Duration == 0 {
+		req.Duration = defaultDuration
+	}
+	if !validDuration(req.Duration) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": durationError})
+		return
 	}
 
 	session := models.Session{
-		Score:             0,
-		Duration:          120, // 120 seconds
-		IsDefaultSettings: req.IsDefaultSettings,
-		StartedAt:         time.Now(),
+		Score:               0,
+		Duration:            req.Duration,
+		IsDefaultSettings:   req.IsDefaultSettings,
+		ServerIssued:        req.ServerIssued,
+		Mode:                models.ModeStandard,
+		LeaderboardEligible: true,
+		StartedAt:           time.Now(),
+	}
+
+	switch req.Mode {
+	case "", models.ModeStandard:
+	case models.ModeSprint:
+		if req.TargetCount == 0 {
+			req.TargetCount = defaultSprintCount
+		}
+		if !validSprintCount(req.TargetCount) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": sprintCountError})
+			return
+		}
+		// A sprint is ranked by time, so the server issues and checks its
+		// problems rather than trusting the client to mark them
+		session.Mode = models.ModeSprint
+		session.TargetCount = req.TargetCount
+		session.ServerIssued = true
+	case models.ModeSuddenDeath, models.ModePenalty:
+		// Wrong answers end or shorten these sessions, so the server issues
+		// and checks their problems
+		session.Mode = req.Mode
+		session.ServerIssued = true
+	case models.ModeWeakFacts:
+		// Weak facts are picked from the user's own history, so the server
+		// has to issue the problems, and the session isn't comparable with
+		// anyone else's
+		if _, exists := c.Get("user_id"); !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Weak facts mode requires an account"})
+			return
+		}
+		session.Mode = models.ModeWeakFacts
+		session.ServerIssued = true
+		session.LeaderboardEligible = false
+		session.FlagReason = "weak facts practice is not ranked"
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "mode must be standard, sprint, sudden_death, penalty or weak_facts"})
+		return
 	}
 
 	// Check if user is authenticated
@@ -38,17 +100,84 @@ func CreateSession(c *gin.Context) {
 		session.UserID = nil
 	}
 
-	if err := database.DB.Create(&session).Error; err != nil {
+	// Work out the settings the session is played with. The client may send
+	// its settings; without them, signed-in users play with their saved
+	// settings. Server-issued sessions are generated from settings the server
+	// knows, so they don't rely on the client's claim about default settings,
+	// and for other anonymous players only that claim is known.
+	var played *models.Settings
+	switch {
+	case req.Settings != nil:
+		if fieldErrors := generator.ValidateSettings(*req.Settings); len(fieldErrors) > 0 {
+			c.JSON(http.StatusUnprocessableEntity, models.ValidationErrorResponse{
+				Error:  "Invalid settings",
+				Fields: fieldErrors,
+			})
+			return
+		}
+		played = req.Settings
+	case session.ServerIssued || session.UserID != nil:
+		settings := h.loadSettings(session.UserID)
+		played = &settings
+	case req.IsDefaultSettings:
+		settings := getDefaultSettings()
+		played = &settings
+	}
+	if played != nil {
+		session.IsDefaultSettings = isDefaultSettings(*played)
+		session.SettingsFingerprint = generator.Fingerprint(*played)
+		session.Settings = snapshotSettings(*played)
+	}
+
+	// Anonymous sessions are protected by a secret only the creator receives,
+	// and tied to the player's anonymous identity, issuing one if needed
+	var secret, anonymousToken string
+	if session.UserID == nil {
+		var err error
+		secret, err = generateSessionSecret()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create session"})
+			return
+		}
+		session.SecretHash = hashSessionSecret(secret)
+
+		if anonymousID, exists := c.Get("anonymous_id"); exists {
+			session.AnonymousID = anonymousID.(string)
+		} else {
+			session.AnonymousID, err = generateAnonymousID()
+			if err == nil {
+				anonymousToken, err = generateAnonymousToken(session.AnonymousID)
+			}
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create session"})
+				return
+			}
+		}
+	}
+
+	if err := h.sessions.Create(&session); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create session"})
 		return
 	}
 
 	c.JSON(http.StatusCreated, models.CreateSessionResponse{
-		SessionID: session.ID,
-		StartedAt: session.StartedAt,
+		SessionID:      session.ID,
+		StartedAt:      session.StartedAt,
+		SessionSecret:  secret,
+		AnonymousToken: anonymousToken,
 	})
 }
 
+// validDuration reports whether a session length is one clients may choose
+func validDuration(seconds int) bool {
+	for _, duration := range sessionDurations {
+		if seconds == duration {
+			return true
+		}
+	}
+	return false
+}
+
 // generateAnonymousName creates a fun anonymous name
 func generateAnonymousName() string {
 	adjectives := []string{
@@ -71,40 +200,90 @@ func generateAnonymousName() string {
 	return fmt.Sprintf("%s %s %d", adjective, noun, number)
 }
 
-// GetSession retrieves a session by ID with all problems
-func GetSession(c *gin.Context) {
-	sessionID := c.Param("id")
+// GetSession retrieves a session by ID with all answered problems and the
+// settings it was played with.
+// Pending server-issued problems are left out so their answers stay private.
+func (h *Handler) GetSession(c *gin.Context) {
+	session, ok := h.findOwnedSession(c)
+	if !ok {
+		return
+	}
+
+	problems, err := h.problems.ListAnswered(session.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch problems"})
+		return
+	}
+	session.Problems = problems
 
-	var session models.Session
-	if err := database.DB.Preload("Problems").First(&session, sessionID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+	settings, err := h.sessions.FindSettings(session.ID)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch session settings"})
 		return
 	}
+	session.Settings = settings
 
 	c.JSON(http.StatusOK, session)
 }
 
-// CompleteSession marks a session as complete and saves the final score
-func CompleteSession(c *gin.Context) {
-	sessionID := c.Param("id")
+// CompleteSession marks a session as complete and saves the final score.
+// Server-issued sessions are scored from their recorded problems instead,
+// and claimed scores that disagree with the recorded problems are flagged.
+// Sprints end by themselves, so completing one abandons it unranked.
+// Sudden death and penalty sessions are always scored by the server.
+// A session ends no later than its deadline, however late it is completed.
+func (h *Handler) CompleteSession(c *gin.Context) {
+	session, ok := h.findOwnedSession(c)
+	if !ok {
+		return
+	}
 
-	var req models.CompleteSessionRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	// A session ended by its mode or closed after running out of time keeps
+	// the score it was given
+	if session.EndedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Session already completed"})
 		return
 	}
 
-	var session models.Session
-	if err := database.DB.First(&session, sessionID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+	recorded, err := h.problems.CountCorrect(session.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to score session"})
 		return
 	}
 
+	score := recorded
+	switch {
+	case session.Mode == models.ModeSprint:
+		// A sprint ends by itself when its target is reached, so completing
+		// one early gives up without a time
+		score = 0
+		session.LeaderboardEligible = false
+		session.FlagReason = fmt.Sprintf("sprint ended after %d of %d correct problems", recorded, session.TargetCount)
+	case session.ServerIssued || serverScored(session.Mode):
+		// Scored from the recorded problems alone
+	default:
+		var req models.CompleteSessionRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Cross-check the claimed score against the recorded problems. A mismatch
+		// keeps the recorded count and takes the session off the leaderboard.
+		if req.Score != recorded {
+			session.LeaderboardEligible = false
+			session.FlagReason = fmt.Sprintf("claimed score %d but %d correct problems were recorded", req.Score, recorded)
+		}
+	}
+
 	now := time.Now()
+	if deadline, limited := session.Deadline(); limited && now.After(deadline) {
+		now = deadline
+	}
 	session.EndedAt = &now
-	session.Score = req.Score
+	session.Score = score
 
-	if err := database.DB.Save(&session).Error; err != nil {
+	if err := h.sessions.Update(session); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update session"})
 		return
 	}
@@ -113,17 +292,20 @@ func CompleteSession(c *gin.Context) {
 }
 
 // DeleteSession deletes a session by ID
-func DeleteSession(c *gin.Context) {
-	sessionID := c.Param("id")
+func (h *Handler) DeleteSession(c *gin.Context) {
+	session, ok := h.findOwnedSession(c)
+	if !ok {
+		return
+	}
 
 	// Delete associated problems first
-	if err := database.DB.Where("session_id = ?", sessionID).Delete(&models.Problem{}).Error; err != nil {
+	if err := h.problems.DeleteBySession(session.ID); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete session problems"})
 		return
 	}
 
 	// Delete the session
-	if err := database.DB.Delete(&models.Session{}, sessionID).Error; err != nil {
+	if err := h.sessions.Delete(session.ID); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete session"})
 		return
 	}
@@ -131,48 +313,46 @@ func DeleteSession(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Session deleted successfully"})
 }
 
-// GetSessions retrieves all sessions, optionally filtered by user_id
-func GetSessions(c *gin.Context) {
+// GetSessions retrieves the caller's ended sessions, by user or anonymous
+// identity
+func (h *Handler) GetSessions(c *gin.Context) {
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
 	offset := (page - 1) * limit
 
-	query := database.DB.Model(&models.Session{})
+	var sessions []models.Session
+	var err error
 
 	// Check if user is authenticated from context (set by auth middleware)
 	if userID, exists := c.Get("user_id"); exists {
 		// User is authenticated, get their sessions
-		query = query.Where("user_id = ?", userID.(uint))
+		sessions, err = h.sessions.ListByUser(userID.(uint), limit, offset)
+	} else if anonymousID, exists := c.Get("anonymous_id"); exists {
+		// Anonymous player, get only the sessions tied to their identity
+		sessions, err = h.sessions.ListByAnonymousID(anonymousID.(string), limit, offset)
 	} else {
-		// User is not authenticated, get anonymous sessions
-		query = query.Where("user_id IS NULL")
+		// No identity at all, so there is no history to show
+		c.JSON(http.StatusOK, []models.SessionSummary{})
+		return
 	}
 
-	var sessions []models.Session
-	if err := query.
-		Order("started_at DESC").
-		Limit(limit).
-		Offset(offset).
-		Find(&sessions).Error; err != nil {
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch sessions"})
 		return
 	}
 
-	// Convert to summaries
+	// Convert to summaries. Only ended sessions are listed, so each has an
+	// end time.
 	summaries := make([]models.SessionSummary, len(sessions))
 	for i, session := range sessions {
-		endedAt := time.Now()
-		if session.EndedAt != nil {
-			endedAt = *session.EndedAt
-		}
-
 		summaries[i] = models.SessionSummary{
 			ID:                session.ID,
 			Score:             session.Score,
 			Duration:          session.Duration,
 			IsDefaultSettings: session.IsDefaultSettings,
 			StartedAt:         session.StartedAt,
-			EndedAt:           endedAt,
+			EndedAt:           *session.EndedAt,
+			AbandonReason:     session.AbandonReason,
 		}
 	}
 
@@ -0,0 +1,235 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/calebwoo/mental-math-trainer/internal/middleware"
+	"github.com/calebwoo/mental-math-trainer/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// createSession creates a default-settings session as the caller
+func (a *testAPI) createSession(headers map[string]string) models.CreateSessionResponse {
+	var created models.CreateSessionResponse
+	status := a.do(http.MethodPost, "/api/sessions", gin.H{"is_default_settings": true}, headers, &created)
+	if status != http.StatusCreated {
+		a.t.Fatalf("create session: status %d", status)
+	}
+	return created
+}
+
+// submit records an answer to "x × y", which the default settings can ask
+// for x from 2 to 12 and y from 2 to 100
+func (a *testAPI) submit(sessionID uint, headers map[string]string, x, y, userAnswer int) int {
+	return a.do(http.MethodPost, fmt.Sprintf("/api/sessions/%d/problems", sessionID), gin.H{
+		"question":      fmt.Sprintf("%d × %d", x, y),
+		"answer":        x * y,
+		"user_answer":   userAnswer,
+		"time_spent_ms": 1500,
+	}, headers, nil)
+}
+
+func TestSessionOwnership(t *testing.T) {
+	a := newTestAPI(t)
+	_, alice := a.user("alice")
+	_, bob := a.user("bob")
+
+	owned := a.createSession(bearer(alice))
+	path := fmt.Sprintf("/api/sessions/%d", owned.SessionID)
+
+	if status := a.do(http.MethodGet, path, nil, bearer(alice), nil); status != http.StatusOK {
+		t.Errorf("owner GET: status %d, want 200", status)
+	}
+	for name, headers := range map[string]map[string]string{"other user": bearer(bob), "no token": nil} {
+		if status := a.do(http.MethodGet, path, nil, headers, nil); status != http.StatusForbidden {
+			t.Errorf("%s GET: status %d, want 403", name, status)
+		}
+		if status := a.do(http.MethodPatch, path+"/complete", gin.H{"score": 1}, headers, nil); status != http.StatusForbidden {
+			t.Errorf("%s complete: status %d, want 403", name, status)
+		}
+		if status := a.submit(owned.SessionID, headers, 3, 4, 12); status != http.StatusForbidden {
+			t.Errorf("%s submit: status %d, want 403", name, status)
+		}
+		if status := a.do(http.MethodDelete, path, nil, headers, nil); status != http.StatusForbidden {
+			t.Errorf("%s DELETE: status %d, want 403", name, status)
+		}
+	}
+
+	// Anonymous sessions need their secret or their creator's anonymous token
+	anonymous := a.createSession(nil)
+	anonPath := fmt.Sprintf("/api/sessions/%d", anonymous.SessionID)
+	if anonymous.SessionSecret == "" || anonymous.AnonymousToken == "" {
+		t.Fatalf("anonymous session without secret or token: %+v", anonymous)
+	}
+	for name, headers := range map[string]map[string]string{
+		"secret": {sessionSecretHeader: anonymous.SessionSecret},
+		"token":  {middleware.AnonymousTokenHeader: anonymous.AnonymousToken},
+	} {
+		if status := a.do(http.MethodGet, anonPath, nil, headers, nil); status != http.StatusOK {
+			t.Errorf("anonymous GET with %s: status %d, want 200", name, status)
+		}
+	}
+	for name, headers := range map[string]map[string]string{
+		"wrong secret":              {sessionSecretHeader: "not-the-secret"},
+		"user token":                bearer(alice),
+		"anonymous token as bearer": bearer(anonymous.AnonymousToken),
+		"nothing":                   nil,
+	} {
+		if status := a.do(http.MethodGet, anonPath, nil, headers, nil); status != http.StatusForbidden {
+			t.Errorf("anonymous GET with %s: status %d, want 403", name, status)
+		}
+	}
+
+	if status := a.do(http.MethodGet, "/api/sessions/9999", nil, bearer(alice), nil); status != http.StatusNotFound {
+		t.Errorf("GET missing session: status %d, want 404", status)
+	}
+	if status := a.do(http.MethodPatch, "/api/sessions/9999/complete", gin.H{"score": 1}, bearer(alice), nil); status != http.StatusNotFound {
+		t.Errorf("complete missing session: status %d, want 404", status)
+	}
+	if status := a.do(http.MethodGet, "/api/sessions/abc", nil, bearer(alice), nil); status != http.StatusBadRequest {
+		t.Errorf("GET non-numeric ID: status %d, want 400", status)
+	}
+
+	// Deleting is also limited to the owner, and the session is gone after
+	if status := a.do(http.MethodDelete, path, nil, bearer(alice), nil); status != http.StatusOK {
+		t.Fatalf("owner DELETE: status %d, want 200", status)
+	}
+	if status := a.do(http.MethodGet, path, nil, bearer(alice), nil); status != http.StatusNotFound {
+		t.Errorf("GET after delete: status %d, want 404", status)
+	}
+}
+
+func TestCompleteSessionCrossChecksScore(t *testing.T) {
+	a := newTestAPI(t)
+	_, alice := a.user("alice")
+
+	play := func(claimed int) models.Session {
+		created := a.createSession(bearer(alice))
+		for _, p := range []struct{ x, y, answer int }{{3, 4, 12}, {7, 8, 56}, {6, 9, 55}} {
+			if status := a.submit(created.SessionID, bearer(alice), p.x, p.y, p.answer); status != http.StatusCreated {
+				t.Fatalf("submit %d × %d: status %d", p.x, p.y, status)
+			}
+		}
+
+		var session models.Session
+		path := fmt.Sprintf("/api/sessions/%d/complete", created.SessionID)
+		if status := a.do(http.MethodPatch, path, gin.H{"score": claimed}, bearer(alice), &session); status != http.StatusOK {
+			t.Fatalf("complete: status %d", status)
+		}
+		return session
+	}
+
+	honest := play(2)
+	if honest.Score != 2 || !honest.LeaderboardEligible || honest.FlagReason != "" {
+		t.Errorf("honest session: score %d, eligible %v, flag %q; want 2, true, none",
+			honest.Score, honest.LeaderboardEligible, honest.FlagReason)
+	}
+
+	inflated := play(50)
+	if inflated.Score != 2 || inflated.LeaderboardEligible || inflated.FlagReason == "" {
+		t.Errorf("inflated session: score %d, eligible %v, flag %q; want 2, false, a reason",
+			inflated.Score, inflated.LeaderboardEligible, inflated.FlagReason)
+	}
+
+	path := fmt.Sprintf("/api/sessions/%d/complete", honest.ID)
+	if status := a.do(http.MethodPatch, path, gin.H{"score": 2}, bearer(alice), nil); status != http.StatusConflict {
+		t.Errorf("second complete: status %d, want %d", status, http.StatusConflict)
+	}
+}
+
+func TestCreateSessionDuration(t *testing.T) {
+	a := newTestAPI(t)
+	_, alice := a.user("alice")
+
+	for _, tt := range []struct {
+		body     any
+		status   int
+		duration int
+	}{
+		{nil, http.StatusCreated, 120},
+		{gin.H{}, http.StatusCreated, 120},
+		{gin.H{"duration": 60}, http.StatusCreated, 60},
+		{gin.H{"duration": 600}, http.StatusCreated, 600},
+		{gin.H{"duration": 45}, http.StatusBadRequest, 0},
+		{gin.H{"duration": -30}, http.StatusBadRequest, 0},
+		{gin.H{"duration": "60"}, http.StatusBadRequest, 0},
+		{gin.H{"mode": 1}, http.StatusBadRequest, 0},
+	} {
+		var created models.CreateSessionResponse
+		if status := a.do(http.MethodPost, "/api/sessions", tt.body, bearer(alice), &created); status != tt.status {
+			t.Errorf("%v: status %d, want %d", tt.body, status, tt.status)
+			continue
+		}
+		if tt.status != http.StatusCreated {
+			continue
+		}
+		session, err := a.repos.Sessions.FindByID(created.SessionID)
+		if err != nil {
+			t.Fatalf("find session: %v", err)
+		}
+		if session.Duration != tt.duration {
+			t.Errorf("%v: duration %d, want %d", tt.body, session.Duration, tt.duration)
+		}
+	}
+}
+
+// startedAgo moves a session's start into the past
+func (a *testAPI) startedAgo(sessionID uint, ago time.Duration) models.Session {
+	session, err := a.repos.Sessions.FindByID(sessionID)
+	if err != nil {
+		a.t.Fatalf("find session: %v", err)
+	}
+	session.StartedAt = time.Now().Add(-ago)
+	if err := a.repos.Sessions.Update(session); err != nil {
+		a.t.Fatalf("update session: %v", err)
+	}
+	return *session
+}
+
+func TestSessionTimeLimit(t *testing.T) {
+	a := newTestAPI(t)
+	_, alice := a.user("alice")
+	limit := 120 * time.Second
+
+	// Problems are accepted during the grace period after the limit
+	created := a.createSession(bearer(alice))
+	a.startedAgo(created.SessionID, limit+testGrace/2)
+	if status := a.submit(created.SessionID, bearer(alice), 3, 4, 12); status != http.StatusCreated {
+		t.Fatalf("submit within grace: status %d, want %d", status, http.StatusCreated)
+	}
+
+	// After it, the session ends at its deadline with the problems recorded in time
+	started := a.startedAgo(created.SessionID, limit+2*testGrace)
+	if status := a.submit(created.SessionID, bearer(alice), 7, 8, 56); status != http.StatusConflict {
+		t.Fatalf("submit after grace: status %d, want %d", status, http.StatusConflict)
+	}
+	session, err := a.repos.Sessions.FindByID(created.SessionID)
+	if err != nil {
+		t.Fatalf("find session: %v", err)
+	}
+	if session.EndedAt == nil || !session.EndedAt.Equal(started.StartedAt.Add(limit)) || session.Score != 1 {
+		t.Errorf("expired session ended at %v with score %d, want %v and 1",
+			session.EndedAt, session.Score, started.StartedAt.Add(limit))
+	}
+	path := fmt.Sprintf("/api/sessions/%d/complete", created.SessionID)
+	if status := a.do(http.MethodPatch, path, gin.H{"score": 1}, bearer(alice), nil); status != http.StatusConflict {
+		t.Errorf("complete expired session: status %d, want %d", status, http.StatusConflict)
+	}
+
+	// Sessions completed late end at their time limit
+	late := a.createSession(bearer(alice))
+	a.submit(late.SessionID, bearer(alice), 3, 4, 12)
+	started = a.startedAgo(late.SessionID, limit+time.Minute)
+	var completed models.Session
+	path = fmt.Sprintf("/api/sessions/%d/complete", late.SessionID)
+	if status := a.do(http.MethodPatch, path, gin.H{"score": 1}, bearer(alice), &completed); status != http.StatusOK {
+		t.Fatalf("complete late: status %d", status)
+	}
+	if completed.EndedAt == nil || !completed.EndedAt.Equal(started.StartedAt.Add(limit)) || completed.Score != 1 {
+		t.Errorf("late session ended at %v with score %d, want %v and 1",
+			completed.EndedAt, completed.Score, started.StartedAt.Add(limit))
+	}
+}
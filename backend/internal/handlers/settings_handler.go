@@ -1,55 +1,69 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 
-	"github.com/calebwoo/mental-math-trainer/internal/database"
+	"github.com/calebwoo/mental-math-trainer/internal/generator"
 	"github.com/calebwoo/mental-math-trainer/internal/models"
+	"github.com/calebwoo/mental-math-trainer/internal/repository"
 	"github.com/gin-gonic/gin"
 )
 
-// GetSettings retrieves settings for a user (or default settings for anonymous)
-func GetSettings(c *gin.Context) {
-	userID := c.Query("user_id")
-
-	if userID == "" {
-		// Return default settings for anonymous users
-		c.JSON(http.StatusOK, getDefaultSettings())
+// GetSettings retrieves settings for the authenticated user (or defaults if none are saved)
+func (h *Handler) GetSettings(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
 		return
 	}
 
-	var settings models.Settings
-	if err := database.DB.Where("user_id = ?", userID).First(&settings).Error; err != nil {
-		// If no settings found, return defaults
-		c.JSON(http.StatusOK, getDefaultSettings())
+	c.JSON(http.StatusOK, h.loadSettings(&userID))
+}
+
+// UpdateSettings updates or creates settings for the authenticated user.
+// The user always comes from the token; a user_id in the body that names
+// someone else is refused.
+func (h *Handler) UpdateSettings(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
 		return
 	}
 
-	c.JSON(http.StatusOK, settings)
-}
-
-// UpdateSettings updates or creates settings for a user
-func UpdateSettings(c *gin.Context) {
 	var settings models.Settings
 	if err := c.ShouldBindJSON(&settings); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	if fieldErrors := generator.ValidateSettings(settings); len(fieldErrors) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, models.ValidationErrorResponse{
+			Error:  "Invalid settings",
+			Fields: fieldErrors,
+		})
+		return
+	}
+
+	if settings.UserID != 0 && settings.UserID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Cannot update another user's settings"})
+		return
+	}
+	settings.UserID = userID
+
 	// Check if settings exist
-	var existing models.Settings
-	result := database.DB.Where("user_id = ?", settings.UserID).First(&existing)
+	existing, err := h.settings.FindByUserID(userID)
 
-	if result.Error != nil {
+	if err != nil {
 		// Create new settings
-		if err := database.DB.Create(&settings).Error; err != nil {
+		settings.ID = 0
+		if err := h.settings.Create(&settings); err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create settings"})
 			return
 		}
 	} else {
 		// Update existing settings
 		settings.ID = existing.ID
-		if err := database.DB.Save(&settings).Error; err != nil {
+		settings.CreatedAt = existing.CreatedAt
+		if err := h.settings.Update(&settings); err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update settings"})
 			return
 		}
@@ -58,28 +72,75 @@ func UpdateSettings(c *gin.Context) {
 	c.JSON(http.StatusOK, settings)
 }
 
+// loadSettings returns the saved settings for a user, or the defaults
+// when the user is anonymous or has never saved any
+func (h *Handler) loadSettings(userID *uint) models.Settings {
+	if userID == nil {
+		return getDefaultSettings()
+	}
+
+	settings, err := h.settings.FindByUserID(*userID)
+	if err != nil {
+		return getDefaultSettings()
+	}
+	return *settings
+}
+
+// isDefaultSettings reports whether the problem configuration matches the defaults
+func isDefaultSettings(settings models.Settings) bool {
+	return settings.OperationSettings == getDefaultSettings().OperationSettings
+}
+
+// snapshotSettings copies the problem configuration of settings for a session
+func snapshotSettings(settings models.Settings) *models.SessionSettings {
+	return &models.SessionSettings{OperationSettings: settings.OperationSettings}
+}
+
+// playedSettings returns the settings a session was created with, from its
+// snapshot or else its fingerprint, never from the user's current settings.
+// It returns ErrNotFound if the session's settings aren't known.
+func (h *Handler) playedSettings(session *models.Session) (models.Settings, error) {
+	snapshot, err := h.sessions.FindSettings(session.ID)
+	if err == nil {
+		return snapshotConfig(snapshot), nil
+	}
+	if !errors.Is(err, repository.ErrNotFound) {
+		return models.Settings{}, err
+	}
+	if session.SettingsFingerprint == "" {
+		return models.Settings{}, repository.ErrNotFound
+	}
+	return generator.ParseFingerprint(session.SettingsFingerprint)
+}
+
+// snapshotConfig returns the problem configuration of a session's settings
+// snapshot
+func snapshotConfig(snapshot *models.SessionSettings) models.Settings {
+	return models.Settings{OperationSettings: snapshot.OperationSettings}
+}
+
 // getDefaultSettings returns the default settings
 func getDefaultSettings() models.Settings {
-	return models.Settings{
-		AdditionEnabled:        true,
-		AdditionMin1:           2,
-		AdditionMax1:           100,
-		AdditionMin2:           2,
-		AdditionMax2:           100,
-		SubtractionEnabled:     true,
-		SubtractionMin1:        2,
-		SubtractionMax1:        100,
-		SubtractionMin2:        2,
-		SubtractionMax2:        100,
-		MultiplicationEnabled:  true,
-		MultiplicationMin1:     2,
-		MultiplicationMax1:     12,
-		MultiplicationMin2:     2,
-		MultiplicationMax2:     100,
-		DivisionEnabled:        true,
-		DivisionMin1:           2,
-		DivisionMax1:           12,
-		DivisionMin2:           2,
-		DivisionMax2:           100,
-	}
+	return models.Settings{OperationSettings: models.OperationSettings{
+		AdditionEnabled:       true,
+		AdditionMin1:          2,
+		AdditionMax1:          100,
+		AdditionMin2:          2,
+		AdditionMax2:          100,
+		SubtractionEnabled:    true,
+		SubtractionMin1:       2,
+		SubtractionMax1:       100,
+		SubtractionMin2:       2,
+		SubtractionMax2:       100,
+		MultiplicationEnabled: true,
+		MultiplicationMin1:    2,
+		MultiplicationMax1:    12,
+		MultiplicationMin2:    2,
+		MultiplicationMax2:    100,
+		DivisionEnabled:       true,
+		DivisionMin1:          2,
+		DivisionMax1:          12,
+		DivisionMin2:          2,
+		DivisionMax2:          100,
+	}}
 }
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/calebwoo/mental-math-trainer/internal/models"
+)
+
+func TestSettingsBelongToTheCaller(t *testing.T) {
+	a := newTestAPI(t)
+	aliceID, alice := a.user("alice")
+	bobID, bob := a.user("bob")
+
+	settings := getDefaultSettings()
+	settings.DivisionEnabled = false
+	settings.MultiplicationMax1 = 9
+
+	// The user comes from the token; naming someone else is refused
+	settings.UserID = bobID
+	if status := a.do(http.MethodPut, "/api/settings", settings, bearer(alice), nil); status != http.StatusForbidden {
+		t.Errorf("PUT for another user: status %d, want 403", status)
+	}
+	settings.UserID = 0
+	if status := a.do(http.MethodPut, "/api/settings", settings, nil, nil); status != http.StatusUnauthorized {
+		t.Errorf("PUT without a token: status %d, want 401", status)
+	}
+
+	var saved models.Settings
+	if status := a.do(http.MethodPut, "/api/settings", settings, bearer(alice), &saved); status != http.StatusOK {
+		t.Fatalf("PUT: status %d", status)
+	}
+	if saved.UserID != aliceID {
+		t.Errorf("saved for user %d, want alice (%d)", saved.UserID, aliceID)
+	}
+
+	invalid := settings
+	invalid.AdditionMin1 = invalid.AdditionMax1 + 1
+	if status := a.do(http.MethodPut, "/api/settings", invalid, bearer(alice), nil); status != http.StatusUnprocessableEntity {
+		t.Errorf("PUT invalid settings: status %d, want 422", status)
+	}
+
+	var got models.Settings
+	if status := a.do(http.MethodGet, "/api/settings", nil, bearer(alice), &got); status != http.StatusOK {
+		t.Fatalf("alice GET: status %d", status)
+	}
+	if got.OperationSettings != settings.OperationSettings {
+		t.Errorf("alice's settings = %+v, want %+v", got.OperationSettings, settings.OperationSettings)
+	}
+	if status := a.do(http.MethodGet, "/api/settings", nil, bearer(bob), &got); status != http.StatusOK {
+		t.Fatalf("bob GET: status %d", status)
+	}
+	if got.OperationSettings != getDefaultSettings().OperationSettings {
+		t.Errorf("bob's settings = %+v, want the defaults", got.OperationSettings)
+	}
+}
@@ -17,9 +17,21 @@ func getJWTSecret() string {
 	return secret
 }
 
+// AnonymousTokenHeader carries the signed identity issued to anonymous players
+const AnonymousTokenHeader = "X-Anonymous-Token"
+
+// AnonymousTokenType is the "typ" claim of anonymous tokens. They are signed
+// with the same secret as user tokens, but never authenticate a user.
+const AnonymousTokenType = "anon"
+
 // OptionalAuth middleware that extracts user info if token is present, but doesn't require it
 func OptionalAuth() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		// Anonymous players identify themselves with a separate token
+		if anonymousID := ParseAnonymousToken(c.GetHeader(AnonymousTokenHeader)); anonymousID != "" {
+			c.Set("anonymous_id", anonymousID)
+		}
+
 		// Get token from Authorization header
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -36,34 +48,69 @@ func OptionalAuth() gin.HandlerFunc {
 			return
 		}
 
-		tokenString := parts[1]
-
 		// Parse and validate token
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, jwt.ErrSignatureInvalid
-			}
-			return []byte(getJWTSecret()), nil
-		})
-
-		if err != nil || !token.Valid {
+		userID, username, ok := parseUserToken(parts[1])
+		if !ok {
 			// Invalid token, continue without user context
 			c.Next()
 			return
 		}
 
-		// Extract claims
-		if claims, ok := token.Claims.(jwt.MapClaims); ok {
-			if userID, ok := claims["user_id"].(float64); ok {
-				c.Set("user_id", uint(userID))
-			}
-			if username, ok := claims["username"].(string); ok {
-				c.Set("username", username)
-			}
+		c.Set("user_id", userID)
+		c.Set("username", username)
+		c.Next()
+	}
+}
+
+// parseUserToken returns the user ID and username of a valid user token.
+// Anonymous tokens are rejected, as is any token without a user ID.
+func parseUserToken(tokenString string) (uint, string, bool) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, jwt.ErrSignatureInvalid
 		}
+		return []byte(getJWTSecret()), nil
+	})
+	if err != nil || !token.Valid {
+		return 0, "", false
+	}
 
-		c.Next()
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || claims["typ"] == AnonymousTokenType {
+		return 0, "", false
 	}
+
+	userID, ok := claims["user_id"].(float64)
+	if !ok || userID < 1 {
+		return 0, "", false
+	}
+	username, _ := claims["username"].(string)
+	return uint(userID), username, true
+}
+
+// ParseAnonymousToken returns the anonymous ID from a valid anonymous token,
+// or an empty string if the token is missing or invalid
+func ParseAnonymousToken(tokenString string) string {
+	if tokenString == "" {
+		return ""
+	}
+
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, jwt.ErrSignatureInvalid
+		}
+		return []byte(getJWTSecret()), nil
+	})
+	if err != nil || !token.Valid {
+		return ""
+	}
+
+	if claims, ok := token.Claims.(jwt.MapClaims); ok && claims["typ"] == AnonymousTokenType {
+		if anonymousID, ok := claims["anonymous_id"].(string); ok {
+			return anonymousID
+		}
+	}
+	return ""
 }
 
 // RequireAuth middleware that requires a valid token
@@ -85,36 +132,16 @@ func RequireAuth() gin.HandlerFunc {
 			return
 		}
 
-		tokenString := parts[1]
-
 		// Parse and validate token
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, jwt.ErrSignatureInvalid
-			}
-			return []byte(getJWTSecret()), nil
-		})
-
-		if err != nil || !token.Valid {
+		userID, username, ok := parseUserToken(parts[1])
+		if !ok {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
 			c.Abort()
 			return
 		}
 
-		// Extract claims
-		if claims, ok := token.Claims.(jwt.MapClaims); ok {
-			if userID, ok := claims["user_id"].(float64); ok {
-				c.Set("user_id", uint(userID))
-			}
-			if username, ok := claims["username"].(string); ok {
-				c.Set("username", username)
-			}
-		} else {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
-			c.Abort()
-			return
-		}
-
+		c.Set("user_id", userID)
+		c.Set("username", username)
 		c.Next()
 	}
 }
\ No newline at end of file
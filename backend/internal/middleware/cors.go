@@ -22,7 +22,7 @@ func SetupCORS() gin.HandlerFunc {
 	}
 
 	config.AllowMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
-	config.AllowHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization"}
+	config.AllowHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Session-Secret", "X-Anonymous-Token"}
 	config.AllowCredentials = true
 
 	return cors.New(config)
@@ -21,19 +21,50 @@ type User struct {
 
 // Session represents a single practice session
 type Session struct {
-	ID                 uint           `gorm:"primaryKey" json:"id"`
-	UserID             *uint          `json:"user_id,omitempty"` // Nullable for anonymous users
-	User               *User          `gorm:"foreignKey:UserID" json:"user,omitempty"`
-	AnonymousName      string         `json:"anonymous_name,omitempty"` // Used when UserID is null
-	Score              int            `json:"score"`
-	Duration           int            `json:"duration"` // in seconds
-	IsDefaultSettings  bool           `json:"is_default_settings" gorm:"default:false"`
-	StartedAt          time.Time      `json:"started_at"`
-	EndedAt            *time.Time     `json:"ended_at,omitempty"`
-	CreatedAt          time.Time      `json:"created_at"`
-	UpdatedAt          time.Time      `json:"updated_at"`
-	DeletedAt          gorm.DeletedAt `gorm:"index" json:"-"`
-	Problems           []Problem      `gorm:"foreignKey:SessionID" json:"problems,omitempty"`
+	ID                  uint             `gorm:"primaryKey" json:"id"`
+	UserID              *uint            `json:"user_id,omitempty"` // Nullable for anonymous users
+	User                *User            `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	AnonymousName       string           `json:"anonymous_name,omitempty"` // Used when UserID is null
+	AnonymousID         string           `gorm:"index" json:"-"`           // Identity of the anonymous player, from their token
+	SecretHash          string           `json:"-"`                        // SHA-256 of the anonymous session secret
+	Score               int              `json:"score"`
+	Duration            int              `json:"duration"` // in seconds
+	IsDefaultSettings   bool             `json:"is_default_settings" gorm:"default:false"`
+	SettingsFingerprint string           `json:"settings_fingerprint,omitempty" gorm:"index"` // Canonical settings the session was played with; empty if unknown
+	ServerIssued        bool             `json:"server_issued" gorm:"default:false"`          // Problems are generated and checked by the server
+	Mode                string           `json:"mode" gorm:"default:standard"`                // One of the Mode constants
+	TargetCount         int              `json:"target_count,omitempty"`                      // Correct problems that finish a sprint
+	WrongAnswers        int              `json:"wrong_answers,omitempty"`                     // Counted in sudden death and penalty modes
+	PenaltySeconds      int              `json:"penalty_seconds,omitempty"`                   // Time taken off a penalty session for wrong answers
+	LeaderboardEligible bool             `json:"leaderboard_eligible"`                        // No GORM default, so false is written on create
+	FlagReason          string           `json:"flag_reason,omitempty"`                       // Why the session was excluded from the leaderboard
+	AbandonReason       string           `json:"abandon_reason,omitempty"`                    // Why the sweeper ended a session that was never completed
+	StartedAt           time.Time        `json:"started_at"`
+	EndedAt             *time.Time       `json:"ended_at,omitempty"`
+	CreatedAt           time.Time        `json:"created_at"`
+	UpdatedAt           time.Time        `json:"updated_at"`
+	DeletedAt           gorm.DeletedAt   `gorm:"index" json:"-"`
+	Problems            []Problem        `gorm:"foreignKey:SessionID" json:"problems,omitempty"`
+	Settings            *SessionSettings `gorm:"foreignKey:SessionID" json:"settings,omitempty"` // Settings the session was played with
+}
+
+// Session modes
+const (
+	ModeStandard    = "standard"     // Problems drawn uniformly from the settings
+	ModeWeakFacts   = "weak_facts"   // Server-issued problems favoring the user's weak facts
+	ModeSprint      = "sprint"       // Solve TargetCount problems; scored by elapsed milliseconds
+	ModeSuddenDeath = "sudden_death" // The first wrong answer ends the session
+	ModePenalty     = "penalty"      // Each wrong answer takes time off the session
+)
+
+// Deadline returns when the session's time runs out, after any penalties.
+// Sprints run until their target is reached, so they have no deadline.
+func (s *Session) Deadline() (time.Time, bool) {
+	if s.Mode == ModeSprint {
+		return time.Time{}, false
+	}
+	remaining := time.Duration(s.Duration-s.PenaltySeconds) * time.Second
+	return s.StartedAt.Add(remaining), true
 }
 
 // Problem represents a single math problem in a session
@@ -41,6 +72,9 @@ type Problem struct {
 	ID          uint      `gorm:"primaryKey" json:"id"`
 	SessionID   uint      `json:"session_id"`
 	Question    string    `json:"question"`
+	Operation   string    `gorm:"index" json:"operation,omitempty"` // Empty if the question couldn't be read
+	Operands    Operands  `json:"operands,omitempty"`
+	Difficulty  string    `json:"difficulty,omitempty"` // Digits in each operand, such as "3x2"
 	Answer      int       `json:"answer"`
 	UserAnswer  *int      `json:"user_answer,omitempty"`
 	TimeSpentMs int       `json:"time_spent_ms"` // milliseconds
@@ -49,7 +83,9 @@ type Problem struct {
 	CreatedAt   time.Time `json:"created_at"`
 }
 
-// Settings represents user preferences for problem generation
+// Settings represents user preferences for problem generation. The columns'
+// defaults are left to the migrations: with a default tag, GORM would write
+// the default in place of a disabled operation or a zero minimum.
 type Settings struct {
 	ID        uint           `gorm:"primaryKey" json:"id"`
 	UserID    uint           `gorm:"uniqueIndex" json:"user_id"`
@@ -57,56 +93,138 @@ type Settings struct {
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
 
+	OperationSettings `gorm:"embedded"`
+}
+
+// SessionSettings is an immutable copy of the settings a session was played
+// with, kept even after the user changes their settings
+type SessionSettings struct {
+	ID        uint      `gorm:"primaryKey" json:"-"`
+	SessionID uint      `gorm:"uniqueIndex" json:"-"`
+	CreatedAt time.Time `json:"-"`
+
+	OperationSettings `gorm:"embedded"`
+}
+
+// OperationSettings are the operations problems are generated for and the
+// ranges of their operands, shared by Settings and SessionSettings
+type OperationSettings struct {
 	// Addition settings
-	AdditionEnabled bool `json:"addition_enabled" gorm:"default:true"`
-	AdditionMin1    int  `json:"addition_min1" gorm:"default:2;column:addition_min1"`
-	AdditionMax1    int  `json:"addition_max1" gorm:"default:100;column:addition_max1"`
-	AdditionMin2    int  `json:"addition_min2" gorm:"default:2;column:addition_min2"`
-	AdditionMax2    int  `json:"addition_max2" gorm:"default:100;column:addition_max2"`
+	AdditionEnabled bool `json:"addition_enabled"`
+	AdditionMin1    int  `json:"addition_min1" gorm:"column:addition_min1"`
+	AdditionMax1    int  `json:"addition_max1" gorm:"column:addition_max1"`
+	AdditionMin2    int  `json:"addition_min2" gorm:"column:addition_min2"`
+	AdditionMax2    int  `json:"addition_max2" gorm:"column:addition_max2"`
 
 	// Subtraction settings
-	SubtractionEnabled bool `json:"subtraction_enabled" gorm:"default:true"`
-	SubtractionMin1    int  `json:"subtraction_min1" gorm:"default:2;column:subtraction_min1"`
-	SubtractionMax1    int  `json:"subtraction_max1" gorm:"default:100;column:subtraction_max1"`
-	SubtractionMin2    int  `json:"subtraction_min2" gorm:"default:2;column:subtraction_min2"`
-	SubtractionMax2    int  `json:"subtraction_max2" gorm:"default:100;column:subtraction_max2"`
+	SubtractionEnabled bool `json:"subtraction_enabled"`
+	SubtractionMin1    int  `json:"subtraction_min1" gorm:"column:subtraction_min1"`
+	SubtractionMax1    int  `json:"subtraction_max1" gorm:"column:subtraction_max1"`
+	SubtractionMin2    int  `json:"subtraction_min2" gorm:"column:subtraction_min2"`
+	SubtractionMax2    int  `json:"subtraction_max2" gorm:"column:subtraction_max2"`
 
 	// Multiplication settings
-	MultiplicationEnabled bool `json:"multiplication_enabled" gorm:"default:true"`
-	MultiplicationMin1    int  `json:"multiplication_min1" gorm:"default:2;column:multiplication_min1"`
-	MultiplicationMax1    int  `json:"multiplication_max1" gorm:"default:12;column:multiplication_max1"`
-	MultiplicationMin2    int  `json:"multiplication_min2" gorm:"default:2;column:multiplication_min2"`
-	MultiplicationMax2    int  `json:"multiplication_max2" gorm:"default:100;column:multiplication_max2"`
+	MultiplicationEnabled bool `json:"multiplication_enabled"`
+	MultiplicationMin1    int  `json:"multiplication_min1" gorm:"column:multiplication_min1"`
+	MultiplicationMax1    int  `json:"multiplication_max1" gorm:"column:multiplication_max1"`
+	MultiplicationMin2    int  `json:"multiplication_min2" gorm:"column:multiplication_min2"`
+	MultiplicationMax2    int  `json:"multiplication_max2" gorm:"column:multiplication_max2"`
 
 	// Division settings
-	DivisionEnabled bool `json:"division_enabled" gorm:"default:false"`
-	DivisionMin1    int  `json:"division_min1" gorm:"default:2;column:division_min1"`
-	DivisionMax1    int  `json:"division_max1" gorm:"default:12;column:division_max1"`
-	DivisionMin2    int  `json:"division_min2" gorm:"default:2;column:division_min2"`
-	DivisionMax2    int  `json:"division_max2" gorm:"default:100;column:division_max2"`
+	DivisionEnabled bool `json:"division_enabled"`
+	DivisionMin1    int  `json:"division_min1" gorm:"column:division_min1"`
+	DivisionMax1    int  `json:"division_max1" gorm:"column:division_max1"`
+	DivisionMin2    int  `json:"division_min2" gorm:"column:division_min2"`
+	DivisionMax2    int  `json:"division_max2" gorm:"column:division_max2"`
+}
+
+// FactSchedule is a user's spaced-repetition progress on one fact, updated
+// every time they answer a problem drilling it
+type FactSchedule struct {
+	ID              uint      `gorm:"primaryKey" json:"-"`
+	UserID          uint      `gorm:"uniqueIndex:idx_fact_schedules_fact" json:"-"`
+	Operation       string    `gorm:"uniqueIndex:idx_fact_schedules_fact" json:"operation"`
+	A               int       `gorm:"uniqueIndex:idx_fact_schedules_fact" json:"a"`
+	B               int       `gorm:"uniqueIndex:idx_fact_schedules_fact" json:"b"`
+	Attempts        int       `json:"attempts"`
+	Correct         int       `json:"correct"`
+	TotalTimeMs     int       `json:"total_time_ms"`
+	Streak          int       `json:"streak"` // consecutive quick, correct answers
+	Ease            float64   `json:"ease"`   // growth factor of the interval
+	IntervalSeconds int       `json:"interval_seconds"`
+	DueAt           time.Time `json:"due_at"`
+	LastSeenAt      time.Time `json:"last_seen_at"`
+	CreatedAt       time.Time `json:"-"`
+	UpdatedAt       time.Time `json:"-"`
+}
+
+// FieldError describes a single invalid field in a request
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// ValidationErrorResponse is returned when a request fails validation
+type ValidationErrorResponse struct {
+	Error  string       `json:"error"`
+	Fields []FieldError `json:"fields"`
 }
 
 // CreateSessionRequest represents the request to create a new session
 type CreateSessionRequest struct {
-	UserID            *uint `json:"user_id,omitempty"`
-	IsDefaultSettings bool  `json:"is_default_settings"`
+	UserID            *uint     `json:"user_id,omitempty"`
+	IsDefaultSettings bool      `json:"is_default_settings"`
+	ServerIssued      bool      `json:"server_issued"`      // Ignores is_default_settings; uses settings if sent, else the user's saved settings
+	Mode              string    `json:"mode"`               // standard (default), sprint, sudden_death, penalty or weak_facts, which is always server-issued
+	Duration          int       `json:"duration"`           // in seconds; 30, 60, 120 (default), 300 or 600
+	TargetCount       int       `json:"target_count"`       // sprint only; 10, 20 (default), 30 or 50
+	Settings          *Settings `json:"settings,omitempty"` // Settings the session is played with; overrides is_default_settings
 }
 
 // CreateSessionResponse represents the response after creating a session
 type CreateSessionResponse struct {
-	SessionID uint      `json:"session_id"`
-	StartedAt time.Time `json:"started_at"`
+	SessionID      uint      `json:"session_id"`
+	StartedAt      time.Time `json:"started_at"`
+	SessionSecret  string    `json:"session_secret,omitempty"`  // Only for anonymous sessions; send as X-Session-Secret
+	AnonymousToken string    `json:"anonymous_token,omitempty"` // Issued on an anonymous player's first session; send as X-Anonymous-Token
 }
 
 // SubmitProblemRequest represents the request to submit a problem answer
 type SubmitProblemRequest struct {
 	Question    string `json:"question" binding:"required"`
+	Operation   string `json:"operation"` // Optional with operands; derived from the question when omitted
+	Operands    []int  `json:"operands"`
 	Answer      int    `json:"answer" binding:"required"`
 	UserAnswer  int    `json:"user_answer" binding:"required"`
 	TimeSpentMs int    `json:"time_spent_ms" binding:"required"`
 	TypoCount   int    `json:"typo_count"`
 }
 
+// IssuedProblem represents a server-issued problem; the answer is kept private
+type IssuedProblem struct {
+	ID        uint      `json:"id"`
+	SessionID uint      `json:"session_id"`
+	Question  string    `json:"question"`
+	IssuedAt  time.Time `json:"issued_at"`
+}
+
+// AnswerProblemRequest represents the request to answer a server-issued problem
+type AnswerProblemRequest struct {
+	UserAnswer  *int `json:"user_answer" binding:"required"`
+	TimeSpentMs int  `json:"time_spent_ms"`
+	TypoCount   int  `json:"typo_count"`
+}
+
+// AnswerProblemResponse represents the server's verdict on an answer
+type AnswerProblemResponse struct {
+	ProblemID    uint `json:"problem_id"`
+	IsCorrect    bool `json:"is_correct"`
+	Answer       int  `json:"answer"`
+	Score        int  `json:"score"`         // correct answers so far in the session
+	SessionEnded bool `json:"session_ended"` // The answer finished the session, such as the last problem of a sprint
+}
+
 // CompleteSessionRequest represents the request to complete a session
 type CompleteSessionRequest struct {
 	Score int `json:"score" binding:"required"`
@@ -120,6 +238,19 @@ type SessionSummary struct {
 	IsDefaultSettings bool      `json:"is_default_settings"`
 	StartedAt         time.Time `json:"started_at"`
 	EndedAt           time.Time `json:"ended_at"`
+	AbandonReason     string    `json:"abandon_reason,omitempty"`
+}
+
+// SessionClaim proves ownership of a single anonymous session
+type SessionClaim struct {
+	SessionID     uint   `json:"session_id" binding:"required"`
+	SessionSecret string `json:"session_secret" binding:"required"`
+}
+
+// AnonymousClaims carries proof of anonymous play to move onto an account
+type AnonymousClaims struct {
+	AnonymousToken string         `json:"anonymous_token,omitempty"`
+	SessionClaims  []SessionClaim `json:"session_claims,omitempty" binding:"omitempty,dive"`
 }
 
 // RegisterRequest represents the request to register a new user
@@ -127,26 +258,103 @@ type RegisterRequest struct {
 	Username string `json:"username" binding:"required,min=3,max=20"`
 	Email    string `json:"email" binding:"required,email"`
 	Password string `json:"password" binding:"required,min=6"`
+	AnonymousClaims
 }
 
 // LoginRequest represents the request to log in
 type LoginRequest struct {
 	Username string `json:"username" binding:"required"`
 	Password string `json:"password" binding:"required"`
+	AnonymousClaims
 }
 
 // AuthResponse represents the response for login/register
 type AuthResponse struct {
-	Token    string `json:"token"`
-	User     User   `json:"user"`
+	Token           string `json:"token"`
+	User            User   `json:"user"`
+	ClaimedSessions int    `json:"claimed_sessions,omitempty"` // Anonymous sessions moved onto the account
 }
 
 // LeaderboardEntry represents a single entry in the leaderboard
 type LeaderboardEntry struct {
-	Rank          int       `json:"rank"`
-	Username      string    `json:"username"`
-	Score         int       `json:"score"`
-	Duration      int       `json:"duration"`
-	StartedAt     time.Time `json:"started_at"`
-	IsAnonymous   bool      `json:"is_anonymous"`
+	Rank        int       `json:"rank"`
+	Username    string    `json:"username"`
+	Score       int       `json:"score"`
+	Duration    int       `json:"duration"`
+	StartedAt   time.Time `json:"started_at"`
+	IsAnonymous bool      `json:"is_anonymous"`
+}
+
+// PlayerLeaderboardEntry represents a player ranked by their best session
+type PlayerLeaderboardEntry struct {
+	Rank        int       `json:"rank"`
+	Username    string    `json:"username"`
+	IsAnonymous bool      `json:"is_anonymous"`
+	BestScore   int       `json:"best_score"`
+	Duration    int       `json:"duration"`
+	BestRunAt   time.Time `json:"best_run_at"`
+	Runs        int       `json:"runs"` // ranked sessions played in the window
+	IsYou       bool      `json:"is_you,omitempty"`
+}
+
+// ConfigurationEntry describes a settings configuration and how often it is played
+type ConfigurationEntry struct {
+	Fingerprint string                   `json:"fingerprint"`
+	IsDefault   bool                     `json:"is_default"`
+	Operations  []ConfigurationOperation `json:"operations"`
+	Sessions    int                      `json:"sessions"`
+	Players     int                      `json:"players"`
+}
+
+// ConfigurationOperation is an enabled operation and its operand ranges
+type ConfigurationOperation struct {
+	Operation string `json:"operation"`
+	Min1      int    `json:"min1"`
+	Max1      int    `json:"max1"`
+	Min2      int    `json:"min2"`
+	Max2      int    `json:"max2"`
+}
+
+// OperationStats summarizes a user's answered problems for one operation
+type OperationStats struct {
+	Operation    string  `json:"operation"`
+	Count        int     `json:"count"`
+	Correct      int     `json:"correct"`
+	Accuracy     float64 `json:"accuracy"` // fraction of problems answered correctly
+	MeanTimeMs   float64 `json:"mean_time_ms"`
+	MedianTimeMs float64 `json:"median_time_ms"`
+	P90TimeMs    int     `json:"p90_time_ms"`
+	TypoRate     float64 `json:"typo_rate"` // fraction of problems with at least one correction
+}
+
+// AnalyticsResponse breaks a user's answered problems down by operation
+type AnalyticsResponse struct {
+	Overall    OperationStats   `json:"overall"`
+	Operations []OperationStats `json:"operations"`
+}
+
+// FactHeatmap is a grid of one operation's facts, with Cells[i][j] holding
+// the fact Rows[i], Columns[j], or null if it was never attempted
+type FactHeatmap struct {
+	Operation string        `json:"operation"`
+	Rows      []int         `json:"rows"`
+	Columns   []int         `json:"columns"`
+	Cells     [][]*FactCell `json:"cells"`
+}
+
+// FactCell summarizes the attempts at a single fact
+type FactCell struct {
+	Attempts   int     `json:"attempts"`
+	Correct    int     `json:"correct"`
+	Accuracy   float64 `json:"accuracy"`
+	MeanTimeMs float64 `json:"mean_time_ms"`
+}
+
+// LeaderboardPositionResponse is the caller's place on the players leaderboard
+// along with the players ranked around them
+type LeaderboardPositionResponse struct {
+	Rank       int                      `json:"rank"`
+	Total      int                      `json:"total"`      // ranked players in the window
+	Percentile float64                  `json:"percentile"` // share of other players ranked below the caller
+	Entries    []PlayerLeaderboardEntry `json:"entries"`
 }
@@ -0,0 +1,56 @@
+package models
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Operands are a problem's operands in question order, stored as a
+// comma-separated list such as "144,12"
+type Operands []int
+
+// GormDataType stores operands in a text column
+func (Operands) GormDataType() string {
+	return "string"
+}
+
+// Value implements driver.Valuer
+func (o Operands) Value() (driver.Value, error) {
+	if len(o) == 0 {
+		return "", nil
+	}
+	parts := make([]string, len(o))
+	for i, operand := range o {
+		parts[i] = strconv.Itoa(operand)
+	}
+	return strings.Join(parts, ","), nil
+}
+
+// Scan implements sql.Scanner
+func (o *Operands) Scan(value interface{}) error {
+	var text string
+	switch v := value.(type) {
+	case nil:
+	case string:
+		text = v
+	case []byte:
+		text = string(v)
+	default:
+		return fmt.Errorf("cannot scan %T into Operands", value)
+	}
+
+	*o = nil
+	if text == "" {
+		return nil
+	}
+	for _, part := range strings.Split(text, ",") {
+		operand, err := strconv.Atoi(part)
+		if err != nil {
+			return fmt.Errorf("invalid operand %q: %w", part, err)
+		}
+		*o = append(*o, operand)
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package practice
+
+import (
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/calebwoo/mental-math-trainer/internal/models"
+)
+
+const (
+	// TargetTimeMs is how quickly a fact should be answered; slower correct
+	// answers still count, but the fact comes back sooner
+	TargetTimeMs = 3000
+
+	initialEase   = 2.5
+	minEase       = 1.3
+	firstInterval = 10 * time.Minute
+	maxInterval   = 180 * 24 * time.Hour
+
+	// newFactChance is how often a weak facts session asks a random problem
+	// instead, so facts the user has never seen get scheduled too
+	newFactChance = 0.2
+
+	// notDueWeight scales down facts that aren't due for review yet
+	notDueWeight = 0.1
+)
+
+// Review updates a schedule after an attempt at its fact, following a
+// simplified SM-2: wrong answers are due again at once, slow answers grow
+// the interval a little and quick correct answers grow it by the ease. The
+// interval only grows when the fact was due, so answering it early doesn't
+// push it out, and it never exceeds maxInterval.
+func Review(schedule *models.FactSchedule, correct bool, timeMs int, now time.Time) {
+	if schedule.Ease == 0 {
+		schedule.Ease = initialEase
+	}
+	schedule.Attempts++
+	schedule.TotalTimeMs += timeMs
+	schedule.LastSeenAt = now
+
+	interval := time.Duration(schedule.IntervalSeconds) * time.Second
+	switch {
+	case !correct:
+		schedule.Streak = 0
+		schedule.Ease = math.Max(minEase, schedule.Ease-0.2)
+		interval = 0
+	case now.Before(schedule.DueAt):
+		// An early correct answer counts, but leaves the schedule alone
+		schedule.Correct++
+		return
+	case timeMs > TargetTimeMs:
+		schedule.Correct++
+		schedule.Streak = 0
+		schedule.Ease = math.Max(minEase, schedule.Ease-0.05)
+		interval = maxDuration(firstInterval, grow(interval, 1.2))
+	default:
+		schedule.Correct++
+		schedule.Streak++
+		if interval == 0 {
+			interval = firstInterval
+		} else {
+			interval = grow(interval, schedule.Ease)
+		}
+		schedule.Ease += 0.05
+	}
+
+	schedule.IntervalSeconds = int(interval / time.Second)
+	schedule.DueAt = now.Add(interval)
+}
+
+// Weight scores how much a fact needs practice. Error rate and slow answers
+// raise it, and facts that aren't due yet are mostly held back.
+func Weight(schedule models.FactSchedule, now time.Time) float64 {
+	if schedule.Attempts == 0 {
+		return 1
+	}
+
+	errorRate := 1 - float64(schedule.Correct)/float64(schedule.Attempts)
+	meanTimeMs := float64(schedule.TotalTimeMs) / float64(schedule.Attempts)
+	weight := (1 + 4*errorRate) * (1 + meanTimeMs/TargetTimeMs)
+	if schedule.DueAt.After(now) {
+		weight *= notDueWeight
+	}
+	return weight
+}
+
+// Pick chooses a scheduled fact to practice at random, weighted by Weight.
+// It returns false when a new random problem should be asked instead.
+func Pick(schedules []models.FactSchedule, now time.Time, rng *rand.Rand) (models.FactSchedule, bool) {
+	if len(schedules) == 0 || rng.Float64() < newFactChance {
+		return models.FactSchedule{}, false
+	}
+
+	weights := make([]float64, len(schedules))
+	total := 0.0
+	for i, schedule := range schedules {
+		weights[i] = Weight(schedule, now)
+		total += weights[i]
+	}
+
+	target := rng.Float64() * total
+	for i, weight := range weights {
+		target -= weight
+		if target < 0 {
+			return schedules[i], true
+		}
+	}
+	return schedules[len(schedules)-1], true
+}
+
+// grow multiplies an interval by factor, up to maxInterval
+func grow(interval time.Duration, factor float64) time.Duration {
+	return time.Duration(math.Min(float64(interval)*factor, float64(maxInterval)))
+}
+
+func maxDuration(a, b time.Duration) time.Duration {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,108 @@
+package practice
+
+import (
+	"testing"
+	"time"
+
+	"github.com/calebwoo/mental-math-trainer/internal/models"
+)
+
+var start = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func TestReviewProgression(t *testing.T) {
+	var schedule models.FactSchedule
+	now := start
+
+	Review(&schedule, true, 1000, now)
+	if got := time.Duration(schedule.IntervalSeconds) * time.Second; got != firstInterval {
+		t.Fatalf("first interval = %v, want %v", got, firstInterval)
+	}
+
+	// Each quick answer given when the fact is due grows the interval
+	previous := schedule.IntervalSeconds
+	for i := 0; i < 5; i++ {
+		now = schedule.DueAt
+		Review(&schedule, true, 1000, now)
+		if schedule.IntervalSeconds <= previous {
+			t.Fatalf("review %d: interval %ds did not grow from %ds", i+2, schedule.IntervalSeconds, previous)
+		}
+		previous = schedule.IntervalSeconds
+	}
+	if schedule.Streak != 6 || schedule.Correct != 6 || schedule.Attempts != 6 {
+		t.Fatalf("streak, correct, attempts = %d, %d, %d, want 6, 6, 6", schedule.Streak, schedule.Correct, schedule.Attempts)
+	}
+}
+
+func TestReviewCapsInterval(t *testing.T) {
+	var schedule models.FactSchedule
+	now := start
+
+	for i := 0; i < 100; i++ {
+		Review(&schedule, true, 1000, now)
+		interval := time.Duration(schedule.IntervalSeconds) * time.Second
+		if interval <= 0 || interval > maxInterval {
+			t.Fatalf("review %d: interval %v outside (0, %v]", i+1, interval, maxInterval)
+		}
+		if !schedule.DueAt.After(now) {
+			t.Fatalf("review %d: due %v is not after %v", i+1, schedule.DueAt, now)
+		}
+		now = schedule.DueAt
+	}
+	if got := time.Duration(schedule.IntervalSeconds) * time.Second; got != maxInterval {
+		t.Fatalf("interval = %v, want the cap %v", got, maxInterval)
+	}
+}
+
+func TestReviewEarlyAnswerKeepsSchedule(t *testing.T) {
+	var schedule models.FactSchedule
+	Review(&schedule, true, 1000, start)
+	Review(&schedule, true, 1000, schedule.DueAt)
+
+	interval, due, ease := schedule.IntervalSeconds, schedule.DueAt, schedule.Ease
+	for i := 0; i < 50; i++ {
+		Review(&schedule, true, 1000, due.Add(-time.Minute))
+	}
+	if schedule.IntervalSeconds != interval || !schedule.DueAt.Equal(due) || schedule.Ease != ease {
+		t.Fatalf("early answers changed the schedule: interval %d -> %d, due %v -> %v, ease %v -> %v",
+			interval, schedule.IntervalSeconds, due, schedule.DueAt, ease, schedule.Ease)
+	}
+	if schedule.Correct != 52 {
+		t.Fatalf("correct = %d, want 52", schedule.Correct)
+	}
+}
+
+func TestReviewWrongAnswerResets(t *testing.T) {
+	var schedule models.FactSchedule
+	now := start
+	for i := 0; i < 3; i++ {
+		Review(&schedule, true, 1000, now)
+		now = schedule.DueAt
+	}
+
+	Review(&schedule, false, 1000, now)
+	if schedule.IntervalSeconds != 0 || !schedule.DueAt.Equal(now) || schedule.Streak != 0 {
+		t.Fatalf("after a wrong answer: interval %d, due %v, streak %d; want 0, %v, 0",
+			schedule.IntervalSeconds, schedule.DueAt, schedule.Streak, now)
+	}
+
+	for i := 0; i < 20; i++ {
+		Review(&schedule, false, 1000, now)
+	}
+	if schedule.Ease != minEase {
+		t.Fatalf("ease = %v, want the floor %v", schedule.Ease, minEase)
+	}
+}
+
+func TestReviewSlowAnswerGrowsLess(t *testing.T) {
+	quick := models.FactSchedule{IntervalSeconds: 3600, Ease: initialEase, DueAt: start}
+	slow := quick
+
+	Review(&quick, true, 1000, start)
+	Review(&slow, true, TargetTimeMs+1, start)
+	if slow.IntervalSeconds >= quick.IntervalSeconds {
+		t.Fatalf("slow interval %ds is not below quick interval %ds", slow.IntervalSeconds, quick.IntervalSeconds)
+	}
+	if slow.Streak != 0 {
+		t.Fatalf("slow answer kept a streak of %d", slow.Streak)
+	}
+}
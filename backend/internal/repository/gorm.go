@@ -0,0 +1,432 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/calebwoo/mental-math-trainer/internal/models"
+	"gorm.io/gorm"
+)
+
+// NewGorm creates repositories backed by a GORM connection. The queries are
+// portable, so the same repositories serve both Postgres and SQLite.
+func NewGorm(db *gorm.DB) Repositories {
+	return Repositories{
+		Users:    &gormUserRepository{db: db},
+		Sessions: &gormSessionRepository{db: db},
+		Problems: &gormProblemRepository{db: db},
+		Settings: &gormSettingsRepository{db: db},
+		Facts:    &gormFactRepository{db: db},
+	}
+}
+
+// translateError maps GORM errors to repository errors
+func translateError(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	return err
+}
+
+type gormUserRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormUserRepository) Create(user *models.User) error {
+	return r.db.Create(user).Error
+}
+
+func (r *gormUserRepository) FindByID(id uint) (*models.User, error) {
+	var user models.User
+	if err := r.db.First(&user, id).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &user, nil
+}
+
+func (r *gormUserRepository) FindByUsername(username string) (*models.User, error) {
+	var user models.User
+	if err := r.db.Where("username = ?", username).First(&user).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &user, nil
+}
+
+func (r *gormUserRepository) ExistsByUsernameOrEmail(username, email string) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.User{}).
+		Where("username = ? OR email = ?", username, email).
+		Count(&count).Error
+	return count > 0, err
+}
+
+type gormSessionRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormSessionRepository) Create(session *models.Session) error {
+	return r.db.Create(session).Error
+}
+
+func (r *gormSessionRepository) FindByID(id uint) (*models.Session, error) {
+	var session models.Session
+	if err := r.db.First(&session, id).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &session, nil
+}
+
+func (r *gormSessionRepository) Update(session *models.Session) error {
+	return r.db.Omit("User", "Problems", "Settings").Save(session).Error
+}
+
+func (r *gormSessionRepository) Delete(id uint) error {
+	return r.db.Delete(&models.Session{}, id).Error
+}
+
+func (r *gormSessionRepository) FindSettings(sessionID uint) (*models.SessionSettings, error) {
+	var settings models.SessionSettings
+	if err := r.db.Where("session_id = ?", sessionID).First(&settings).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &settings, nil
+}
+
+func (r *gormSessionRepository) ListByUser(userID uint, limit, offset int) ([]models.Session, error) {
+	var sessions []models.Session
+	err := r.db.
+		Where("user_id = ?", userID).
+		Where("ended_at IS NOT NULL").
+		Order("started_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&sessions).Error
+	return sessions, err
+}
+
+func (r *gormSessionRepository) ListByAnonymousID(anonymousID string, limit, offset int) ([]models.Session, error) {
+	var sessions []models.Session
+	err := r.db.
+		Where("user_id IS NULL AND anonymous_id = ?", anonymousID).
+		Where("ended_at IS NOT NULL").
+		Order("started_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&sessions).Error
+	return sessions, err
+}
+
+func (r *gormSessionRepository) ListOpen(startedBefore time.Time) ([]models.Session, error) {
+	var sessions []models.Session
+	err := r.db.
+		Where("ended_at IS NULL AND started_at < ?", startedBefore.Local()).
+		Order("started_at").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+func (r *gormSessionRepository) ClaimAnonymous(anonymousID string, userID uint) (int, error) {
+	result := r.db.Model(&models.Session{}).
+		Where("user_id IS NULL AND anonymous_id = ?", anonymousID).
+		Update("user_id", userID)
+	return int(result.RowsAffected), result.Error
+}
+
+func (r *gormSessionRepository) Leaderboard(query LeaderboardQuery) ([]models.Session, error) {
+	var sessions []models.Session
+	err := r.db.
+		Preload("User").
+		Scopes(leaderboardScope(query)).
+		Order(scoreOrder(query)).
+		Order("started_at").
+		Order("id").
+		Limit(query.Limit).
+		Offset(query.Offset).
+		Find(&sessions).Error
+	return sessions, err
+}
+
+// playerKey identifies who played a session: the user, else the anonymous
+// identity, else the session itself for anonymous sessions that predate
+// anonymous identities. Those store an empty anonymous ID rather than NULL.
+const playerKey = "COALESCE('user:' || CAST(user_id AS TEXT), 'anon:' || NULLIF(anonymous_id, ''), 'session:' || CAST(id AS TEXT))"
+
+func (r *gormSessionRepository) PlayerLeaderboard(query LeaderboardQuery) ([]PlayerStanding, error) {
+	var best []struct {
+		ID   uint
+		Runs int
+	}
+	err := r.bestSessions(query).
+		Select("id, runs").
+		Order(scoreOrder(query)).
+		Order("started_at").
+		Order("id").
+		Limit(query.Limit).
+		Offset(query.Offset).
+		Scan(&best).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(best) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]uint, len(best))
+	for i, b := range best {
+		ids[i] = b.ID
+	}
+
+	var sessions []models.Session
+	if err := r.db.Preload("User").Where("id IN ?", ids).Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]models.Session, len(sessions))
+	for _, session := range sessions {
+		byID[session.ID] = session
+	}
+
+	standings := make([]PlayerStanding, 0, len(best))
+	for _, b := range best {
+		if session, ok := byID[b.ID]; ok {
+			standings = append(standings, PlayerStanding{Best: session, Runs: b.Runs})
+		}
+	}
+	return standings, nil
+}
+
+func (r *gormSessionRepository) PlayerPosition(query LeaderboardQuery, userID uint) (int, int, error) {
+	var mine struct {
+		ID        uint
+		Score     int
+		StartedAt time.Time
+	}
+	err := r.bestSessions(query).
+		Select("id, score, started_at").
+		Where("user_id = ?", userID).
+		Take(&mine).Error
+	if err != nil {
+		return 0, 0, translateError(err)
+	}
+
+	// Count the players ordered ahead of the user's best session
+	better := "score > ?"
+	if query.lowerIsBetter() {
+		better = "score < ?"
+	}
+	var ahead, total int64
+	startedAt := mine.StartedAt.Local()
+	err = r.bestSessions(query).
+		Where(better+" OR (score = ? AND (started_at < ? OR (started_at = ? AND id < ?)))",
+			mine.Score, mine.Score, startedAt, startedAt, mine.ID).
+		Count(&ahead).Error
+	if err != nil {
+		return 0, 0, err
+	}
+	if err := r.bestSessions(query).Count(&total).Error; err != nil {
+		return 0, 0, err
+	}
+	return int(ahead), int(total), nil
+}
+
+func (r *gormSessionRepository) PopularConfigurations(query LeaderboardQuery) ([]ConfigurationStats, error) {
+	var stats []ConfigurationStats
+	err := r.db.Model(&models.Session{}).
+		Where("settings_fingerprint <> ''").
+		Where("leaderboard_eligible = ?", true).
+		Scopes(partitionScope(query)).
+		Select("settings_fingerprint AS fingerprint, COUNT(*) AS sessions, " +
+			"COUNT(DISTINCT " + playerKey + ") AS players").
+		Group("settings_fingerprint").
+		Order("players DESC").
+		Order("sessions DESC").
+		Order("settings_fingerprint").
+		Limit(query.Limit).
+		Offset(query.Offset).
+		Scan(&stats).Error
+	return stats, err
+}
+
+// bestSessions selects each player's best ranked session, with their number
+// of runs, as the table "ranked"
+func (r *gormSessionRepository) bestSessions(query LeaderboardQuery) *gorm.DB {
+	// Rank each player's sessions and keep only their best one
+	ranked := r.db.Model(&models.Session{}).
+		Scopes(leaderboardScope(query)).
+		Select("id, user_id, score, started_at, " +
+			"ROW_NUMBER() OVER (PARTITION BY " + playerKey + " ORDER BY " + scoreOrder(query) + ", started_at, id) AS player_rank, " +
+			"COUNT(*) OVER (PARTITION BY " + playerKey + ") AS runs")
+
+	return r.db.Table("(?) AS ranked", ranked).Where("player_rank = 1")
+}
+
+// leaderboardScope restricts a query to the sessions a leaderboard ranks
+func leaderboardScope(query LeaderboardQuery) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if query.Fingerprint != "" {
+			db = db.Where("settings_fingerprint = ?", query.Fingerprint)
+		} else {
+			db = db.Where("is_default_settings = ?", true)
+		}
+		if query.lowerIsBetter() {
+			// Unfinished sessions have no time to rank
+			db = db.Where("ended_at IS NOT NULL")
+		}
+		return db.
+			Where("leaderboard_eligible = ?", true).
+			Scopes(partitionScope(query))
+	}
+}
+
+// scoreOrder orders sessions from the best score to the worst
+func scoreOrder(query LeaderboardQuery) string {
+	if query.lowerIsBetter() {
+		return "score ASC"
+	}
+	return "score DESC"
+}
+
+// partitionScope restricts sessions to the query's mode, time window and
+// length
+func partitionScope(query LeaderboardQuery) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if query.Mode != "" {
+			db = db.Where("mode = ?", query.Mode)
+		}
+		if query.TargetCount != 0 {
+			db = db.Where("target_count = ?", query.TargetCount)
+		}
+		if query.Duration != 0 {
+			db = db.Where("duration = ?", query.Duration)
+		}
+		if query.Since != nil {
+			// Timestamps are written in server local time and SQLite compares
+			// them as text, so compare in the same zone
+			db = db.Where("started_at >= ?", query.Since.Local())
+		}
+		return db
+	}
+}
+
+type gormProblemRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormProblemRepository) Create(problem *models.Problem) error {
+	return r.db.Create(problem).Error
+}
+
+func (r *gormProblemRepository) Update(problem *models.Problem) error {
+	return r.db.Save(problem).Error
+}
+
+func (r *gormProblemRepository) FindInSession(sessionID, problemID uint) (*models.Problem, error) {
+	var problem models.Problem
+	if err := r.db.Where("session_id = ?", sessionID).First(&problem, problemID).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &problem, nil
+}
+
+func (r *gormProblemRepository) FindPending(sessionID uint) (*models.Problem, error) {
+	var problem models.Problem
+	err := r.db.
+		Where("session_id = ? AND user_answer IS NULL", sessionID).
+		Order("id").
+		First(&problem).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &problem, nil
+}
+
+func (r *gormProblemRepository) ListAnswered(sessionID uint) ([]models.Problem, error) {
+	var problems []models.Problem
+	err := r.db.
+		Where("session_id = ? AND user_answer IS NOT NULL", sessionID).
+		Order("id").
+		Find(&problems).Error
+	return problems, err
+}
+
+func (r *gormProblemRepository) ListAnsweredByUser(filter ProblemFilter) ([]models.Problem, error) {
+	db := r.db.
+		Select("problems.*").
+		Joins("JOIN sessions ON sessions.id = problems.session_id AND sessions.deleted_at IS NULL").
+		Where("sessions.user_id = ? AND problems.user_answer IS NOT NULL", filter.UserID)
+	if filter.From != nil {
+		db = db.Where("sessions.started_at >= ?", filter.From.Local())
+	}
+	if filter.To != nil {
+		db = db.Where("sessions.started_at < ?", filter.To.Local())
+	}
+	if len(filter.SessionIDs) > 0 {
+		db = db.Where("problems.session_id IN ?", filter.SessionIDs)
+	}
+	if filter.Operation != "" {
+		db = db.Where("problems.operation = ?", filter.Operation)
+	}
+
+	var problems []models.Problem
+	err := db.Order("problems.id").Find(&problems).Error
+	return problems, err
+}
+
+func (r *gormProblemRepository) CountCorrect(sessionID uint) (int, error) {
+	var count int64
+	err := r.db.Model(&models.Problem{}).
+		Where("session_id = ? AND is_correct = ?", sessionID, true).
+		Count(&count).Error
+	return int(count), err
+}
+
+func (r *gormProblemRepository) DeleteBySession(sessionID uint) error {
+	return r.db.Where("session_id = ?", sessionID).Delete(&models.Problem{}).Error
+}
+
+type gormSettingsRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormSettingsRepository) FindByUserID(userID uint) (*models.Settings, error) {
+	var settings models.Settings
+	if err := r.db.Where("user_id = ?", userID).First(&settings).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &settings, nil
+}
+
+func (r *gormSettingsRepository) Create(settings *models.Settings) error {
+	return r.db.Create(settings).Error
+}
+
+func (r *gormSettingsRepository) Update(settings *models.Settings) error {
+	return r.db.Save(settings).Error
+}
+
+type gormFactRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormFactRepository) Find(userID uint, operation string, a, b int) (*models.FactSchedule, error) {
+	var schedule models.FactSchedule
+	err := r.db.
+		Where("user_id = ? AND operation = ? AND a = ? AND b = ?", userID, operation, a, b).
+		First(&schedule).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &schedule, nil
+}
+
+func (r *gormFactRepository) ListByUser(userID uint, operations []string) ([]models.FactSchedule, error) {
+	var schedules []models.FactSchedule
+	err := r.db.
+		Where("user_id = ? AND operation IN ?", userID, operations).
+		Order("id").
+		Find(&schedules).Error
+	return schedules, err
+}
+
+func (r *gormFactRepository) Save(schedule *models.FactSchedule) error {
+	return r.db.Save(schedule).Error
+}
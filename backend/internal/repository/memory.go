@@ -0,0 +1,655 @@
+package repository
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/calebwoo/mental-math-trainer/internal/models"
+)
+
+// NewMemory creates repositories that keep everything in memory.
+// Records are copied in and out, so callers never share state with the store.
+func NewMemory() Repositories {
+	store := &memoryStore{
+		nextIDs:  make(map[string]uint),
+		users:    make(map[uint]models.User),
+		sessions: make(map[uint]models.Session),
+		problems: make(map[uint]models.Problem),
+		settings: make(map[uint]models.Settings),
+
+		sessionSettings: make(map[uint]models.SessionSettings),
+		facts:           make(map[uint]models.FactSchedule),
+	}
+
+	return Repositories{
+		Users:    &memoryUserRepository{store},
+		Sessions: &memorySessionRepository{store},
+		Problems: &memoryProblemRepository{store},
+		Settings: &memorySettingsRepository{store},
+		Facts:    &memoryFactRepository{store},
+	}
+}
+
+// memoryStore holds the tables shared by the in-memory repositories
+type memoryStore struct {
+	mu       sync.RWMutex
+	nextIDs  map[string]uint // per table, like auto-increment keys
+	users    map[uint]models.User
+	sessions map[uint]models.Session
+	problems map[uint]models.Problem
+	settings map[uint]models.Settings
+
+	sessionSettings map[uint]models.SessionSettings // keyed by session ID
+	facts           map[uint]models.FactSchedule
+}
+
+// newID returns the next primary key of a table; callers must hold the write lock
+func (s *memoryStore) newID(table string) uint {
+	s.nextIDs[table]++
+	return s.nextIDs[table]
+}
+
+type memoryUserRepository struct {
+	store *memoryStore
+}
+
+func (r *memoryUserRepository) Create(user *models.User) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := time.Now()
+	user.ID = r.store.newID("users")
+	user.CreatedAt = now
+	user.UpdatedAt = now
+
+	stored := *user
+	stored.Sessions = nil
+	stored.Settings = nil
+	r.store.users[user.ID] = stored
+	return nil
+}
+
+func (r *memoryUserRepository) FindByID(id uint) (*models.User, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	user, ok := r.store.users[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &user, nil
+}
+
+func (r *memoryUserRepository) FindByUsername(username string) (*models.User, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, user := range r.store.users {
+		if user.Username == username {
+			return &user, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryUserRepository) ExistsByUsernameOrEmail(username, email string) (bool, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, user := range r.store.users {
+		if user.Username == username || user.Email == email {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+type memorySessionRepository struct {
+	store *memoryStore
+}
+
+func (r *memorySessionRepository) Create(session *models.Session) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := time.Now()
+	session.ID = r.store.newID("sessions")
+	session.CreatedAt = now
+	session.UpdatedAt = now
+
+	r.store.sessions[session.ID] = copySession(*session)
+
+	if session.Settings != nil {
+		session.Settings.ID = r.store.newID("session_settings")
+		session.Settings.SessionID = session.ID
+		session.Settings.CreatedAt = now
+		r.store.sessionSettings[session.ID] = *session.Settings
+	}
+	return nil
+}
+
+func (r *memorySessionRepository) FindByID(id uint) (*models.Session, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	session, ok := r.store.sessions[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	session = copySession(session)
+	return &session, nil
+}
+
+func (r *memorySessionRepository) FindSettings(sessionID uint) (*models.SessionSettings, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	settings, ok := r.store.sessionSettings[sessionID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &settings, nil
+}
+
+func (r *memorySessionRepository) Update(session *models.Session) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.sessions[session.ID]; !ok {
+		return ErrNotFound
+	}
+	session.UpdatedAt = time.Now()
+	r.store.sessions[session.ID] = copySession(*session)
+	return nil
+}
+
+func (r *memorySessionRepository) Delete(id uint) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	delete(r.store.sessions, id)
+	delete(r.store.sessionSettings, id)
+	return nil
+}
+
+func (r *memorySessionRepository) ListByUser(userID uint, limit, offset int) ([]models.Session, error) {
+	return r.list(func(s models.Session) bool {
+		return s.UserID != nil && *s.UserID == userID && s.EndedAt != nil
+	}, limit, offset), nil
+}
+
+func (r *memorySessionRepository) ListByAnonymousID(anonymousID string, limit, offset int) ([]models.Session, error) {
+	return r.list(func(s models.Session) bool {
+		return s.UserID == nil && s.AnonymousID == anonymousID && s.EndedAt != nil
+	}, limit, offset), nil
+}
+
+func (r *memorySessionRepository) ListOpen(startedBefore time.Time) ([]models.Session, error) {
+	sessions := r.list(func(s models.Session) bool {
+		return s.EndedAt == nil && s.StartedAt.Before(startedBefore)
+	}, 0, 0)
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].StartedAt.Before(sessions[j].StartedAt)
+	})
+	return sessions, nil
+}
+
+// list returns matching sessions newest first
+func (r *memorySessionRepository) list(match func(models.Session) bool, limit, offset int) []models.Session {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var sessions []models.Session
+	for _, session := range r.store.sessions {
+		if match(session) {
+			sessions = append(sessions, copySession(session))
+		}
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].StartedAt.After(sessions[j].StartedAt)
+	})
+	return paginate(sessions, limit, offset)
+}
+
+func (r *memorySessionRepository) ClaimAnonymous(anonymousID string, userID uint) (int, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	claimed := 0
+	for id, session := range r.store.sessions {
+		if session.UserID == nil && session.AnonymousID == anonymousID {
+			uid := userID
+			session.UserID = &uid
+			session.UpdatedAt = time.Now()
+			r.store.sessions[id] = session
+			claimed++
+		}
+	}
+	return claimed, nil
+}
+
+func (r *memorySessionRepository) Leaderboard(query LeaderboardQuery) ([]models.Session, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	sessions := r.ranked(query)
+	return paginate(sessions, query.Limit, query.Offset), nil
+}
+
+func (r *memorySessionRepository) PlayerLeaderboard(query LeaderboardQuery) ([]PlayerStanding, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	return paginate(r.standings(query), query.Limit, query.Offset), nil
+}
+
+func (r *memorySessionRepository) PlayerPosition(query LeaderboardQuery, userID uint) (int, int, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	standings := r.standings(query)
+	for i, standing := range standings {
+		if standing.Best.UserID != nil && *standing.Best.UserID == userID {
+			return i, len(standings), nil
+		}
+	}
+	return 0, 0, ErrNotFound
+}
+
+// standings returns each player's best session in rank order; callers must
+// hold the lock
+func (r *memorySessionRepository) standings(query LeaderboardQuery) []PlayerStanding {
+	// Sessions are already in rank order, so each player's first is their best
+	var standings []PlayerStanding
+	index := make(map[string]int)
+	for _, session := range r.ranked(query) {
+		key := playerKeyOf(session)
+		if i, ok := index[key]; ok {
+			standings[i].Runs++
+			continue
+		}
+		index[key] = len(standings)
+		standings = append(standings, PlayerStanding{Best: session, Runs: 1})
+	}
+	return standings
+}
+
+// ranked returns the sessions matching a leaderboard query in rank order;
+// callers must hold the lock
+func (r *memorySessionRepository) ranked(query LeaderboardQuery) []models.Session {
+	var sessions []models.Session
+	for _, session := range r.store.sessions {
+		if query.Fingerprint != "" {
+			if session.SettingsFingerprint != query.Fingerprint {
+				continue
+			}
+		} else if !session.IsDefaultSettings {
+			continue
+		}
+		if !session.LeaderboardEligible || !inPartition(session, query) {
+			continue
+		}
+		if query.lowerIsBetter() && session.EndedAt == nil {
+			continue
+		}
+		sessions = append(sessions, r.withUser(copySession(session)))
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		if sessions[i].Score != sessions[j].Score {
+			return (sessions[i].Score > sessions[j].Score) != query.lowerIsBetter()
+		}
+		if !sessions[i].StartedAt.Equal(sessions[j].StartedAt) {
+			return sessions[i].StartedAt.Before(sessions[j].StartedAt)
+		}
+		return sessions[i].ID < sessions[j].ID
+	})
+	return sessions
+}
+
+func (r *memorySessionRepository) PopularConfigurations(query LeaderboardQuery) ([]ConfigurationStats, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	index := make(map[string]int)
+	players := make(map[string]map[string]bool)
+	var stats []ConfigurationStats
+	for _, session := range r.store.sessions {
+		fingerprint := session.SettingsFingerprint
+		if fingerprint == "" || !session.LeaderboardEligible || !inPartition(session, query) {
+			continue
+		}
+		i, ok := index[fingerprint]
+		if !ok {
+			i = len(stats)
+			index[fingerprint] = i
+			players[fingerprint] = make(map[string]bool)
+			stats = append(stats, ConfigurationStats{Fingerprint: fingerprint})
+		}
+		stats[i].Sessions++
+		players[fingerprint][playerKeyOf(session)] = true
+	}
+	for i := range stats {
+		stats[i].Players = len(players[stats[i].Fingerprint])
+	}
+
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Players != stats[j].Players {
+			return stats[i].Players > stats[j].Players
+		}
+		if stats[i].Sessions != stats[j].Sessions {
+			return stats[i].Sessions > stats[j].Sessions
+		}
+		return stats[i].Fingerprint < stats[j].Fingerprint
+	})
+	return paginate(stats, query.Limit, query.Offset), nil
+}
+
+// inPartition reports whether a session matches the query's mode and length
+// and started within its time window
+func inPartition(session models.Session, query LeaderboardQuery) bool {
+	if query.Mode != "" && session.Mode != query.Mode {
+		return false
+	}
+	if query.TargetCount != 0 && session.TargetCount != query.TargetCount {
+		return false
+	}
+	if query.Duration != 0 && session.Duration != query.Duration {
+		return false
+	}
+	return query.Since == nil || !session.StartedAt.Before(*query.Since)
+}
+
+// playerKeyOf identifies who played a session, matching the SQL playerKey
+func playerKeyOf(session models.Session) string {
+	switch {
+	case session.UserID != nil:
+		return fmt.Sprintf("user:%d", *session.UserID)
+	case session.AnonymousID != "":
+		return "anon:" + session.AnonymousID
+	default:
+		return fmt.Sprintf("session:%d", session.ID)
+	}
+}
+
+// withUser attaches a copy of the session's user; callers must hold the lock
+func (r *memorySessionRepository) withUser(session models.Session) models.Session {
+	if session.UserID != nil {
+		if user, ok := r.store.users[*session.UserID]; ok {
+			session.User = &user
+		}
+	}
+	return session
+}
+
+// copySession copies a session's pointer fields and drops its associations,
+// which are stored in their own tables
+func copySession(session models.Session) models.Session {
+	if session.UserID != nil {
+		userID := *session.UserID
+		session.UserID = &userID
+	}
+	if session.EndedAt != nil {
+		endedAt := *session.EndedAt
+		session.EndedAt = &endedAt
+	}
+	session.User = nil
+	session.Problems = nil
+	session.Settings = nil
+	return session
+}
+
+type memoryProblemRepository struct {
+	store *memoryStore
+}
+
+func (r *memoryProblemRepository) Create(problem *models.Problem) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	problem.ID = r.store.newID("problems")
+	problem.CreatedAt = time.Now()
+	r.store.problems[problem.ID] = copyProblem(*problem)
+	return nil
+}
+
+func (r *memoryProblemRepository) Update(problem *models.Problem) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.problems[problem.ID]; !ok {
+		return ErrNotFound
+	}
+	r.store.problems[problem.ID] = copyProblem(*problem)
+	return nil
+}
+
+func (r *memoryProblemRepository) FindInSession(sessionID, problemID uint) (*models.Problem, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	problem, ok := r.store.problems[problemID]
+	if !ok || problem.SessionID != sessionID {
+		return nil, ErrNotFound
+	}
+	problem = copyProblem(problem)
+	return &problem, nil
+}
+
+func (r *memoryProblemRepository) FindPending(sessionID uint) (*models.Problem, error) {
+	for _, problem := range r.bySession(sessionID) {
+		if problem.UserAnswer == nil {
+			return &problem, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryProblemRepository) ListAnswered(sessionID uint) ([]models.Problem, error) {
+	var answered []models.Problem
+	for _, problem := range r.bySession(sessionID) {
+		if problem.UserAnswer != nil {
+			answered = append(answered, problem)
+		}
+	}
+	return answered, nil
+}
+
+func (r *memoryProblemRepository) ListAnsweredByUser(filter ProblemFilter) ([]models.Problem, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	sessionIDs := make(map[uint]bool, len(filter.SessionIDs))
+	for _, id := range filter.SessionIDs {
+		sessionIDs[id] = true
+	}
+
+	var problems []models.Problem
+	for _, problem := range r.store.problems {
+		session, ok := r.store.sessions[problem.SessionID]
+		if !ok || session.UserID == nil || *session.UserID != filter.UserID || problem.UserAnswer == nil {
+			continue
+		}
+		if filter.From != nil && session.StartedAt.Before(*filter.From) {
+			continue
+		}
+		if filter.To != nil && !session.StartedAt.Before(*filter.To) {
+			continue
+		}
+		if len(sessionIDs) > 0 && !sessionIDs[problem.SessionID] {
+			continue
+		}
+		if filter.Operation != "" && problem.Operation != filter.Operation {
+			continue
+		}
+		problems = append(problems, copyProblem(problem))
+	}
+
+	sort.Slice(problems, func(i, j int) bool {
+		return problems[i].ID < problems[j].ID
+	})
+	return problems, nil
+}
+
+func (r *memoryProblemRepository) CountCorrect(sessionID uint) (int, error) {
+	count := 0
+	for _, problem := range r.bySession(sessionID) {
+		if problem.IsCorrect {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *memoryProblemRepository) DeleteBySession(sessionID uint) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for id, problem := range r.store.problems {
+		if problem.SessionID == sessionID {
+			delete(r.store.problems, id)
+		}
+	}
+	return nil
+}
+
+// bySession returns copies of a session's problems ordered by ID
+func (r *memoryProblemRepository) bySession(sessionID uint) []models.Problem {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var problems []models.Problem
+	for _, problem := range r.store.problems {
+		if problem.SessionID == sessionID {
+			problems = append(problems, copyProblem(problem))
+		}
+	}
+
+	sort.Slice(problems, func(i, j int) bool {
+		return problems[i].ID < problems[j].ID
+	})
+	return problems
+}
+
+// copyProblem copies a problem so the stored answer pointer and operands
+// aren't shared
+func copyProblem(problem models.Problem) models.Problem {
+	if problem.UserAnswer != nil {
+		answer := *problem.UserAnswer
+		problem.UserAnswer = &answer
+	}
+	if problem.Operands != nil {
+		problem.Operands = append(models.Operands(nil), problem.Operands...)
+	}
+	return problem
+}
+
+type memorySettingsRepository struct {
+	store *memoryStore
+}
+
+func (r *memorySettingsRepository) FindByUserID(userID uint) (*models.Settings, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, settings := range r.store.settings {
+		if settings.UserID == userID {
+			return &settings, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memorySettingsRepository) Create(settings *models.Settings) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := time.Now()
+	settings.ID = r.store.newID("settings")
+	settings.CreatedAt = now
+	settings.UpdatedAt = now
+	r.store.settings[settings.ID] = *settings
+	return nil
+}
+
+func (r *memorySettingsRepository) Update(settings *models.Settings) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.settings[settings.ID]; !ok {
+		return ErrNotFound
+	}
+	settings.UpdatedAt = time.Now()
+	r.store.settings[settings.ID] = *settings
+	return nil
+}
+
+type memoryFactRepository struct {
+	store *memoryStore
+}
+
+func (r *memoryFactRepository) Find(userID uint, operation string, a, b int) (*models.FactSchedule, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, schedule := range r.store.facts {
+		if schedule.UserID == userID && schedule.Operation == operation && schedule.A == a && schedule.B == b {
+			return &schedule, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryFactRepository) ListByUser(userID uint, operations []string) ([]models.FactSchedule, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	wanted := make(map[string]bool, len(operations))
+	for _, op := range operations {
+		wanted[op] = true
+	}
+
+	var schedules []models.FactSchedule
+	for _, schedule := range r.store.facts {
+		if schedule.UserID == userID && wanted[schedule.Operation] {
+			schedules = append(schedules, schedule)
+		}
+	}
+	sort.Slice(schedules, func(i, j int) bool {
+		return schedules[i].ID < schedules[j].ID
+	})
+	return schedules, nil
+}
+
+func (r *memoryFactRepository) Save(schedule *models.FactSchedule) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := time.Now()
+	if schedule.ID == 0 {
+		schedule.ID = r.store.newID("fact_schedules")
+		schedule.CreatedAt = now
+	} else if _, ok := r.store.facts[schedule.ID]; !ok {
+		return ErrNotFound
+	}
+	schedule.UpdatedAt = now
+	r.store.facts[schedule.ID] = *schedule
+	return nil
+}
+
+// paginate applies a limit and offset to a slice; a limit <= 0 means no limit
+func paginate[T any](items []T, limit, offset int) []T {
+	if offset >= len(items) {
+		return nil
+	}
+	if offset > 0 {
+		items = items[offset:]
+	}
+	if limit > 0 && limit < len(items) {
+		items = items[:limit]
+	}
+	return items
+}
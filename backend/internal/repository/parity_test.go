@@ -0,0 +1,319 @@
+package repository_test
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/calebwoo/mental-math-trainer/internal/database"
+	"github.com/calebwoo/mental-math-trainer/internal/models"
+	"github.com/calebwoo/mental-math-trainer/internal/repository"
+	"gorm.io/gorm"
+)
+
+// newGormRepositories migrates a fresh SQLite database and returns its
+// repositories
+func newGormRepositories(t *testing.T) repository.Repositories {
+	t.Setenv("DATABASE_URL", "file:"+filepath.Join(t.TempDir(), "test.db"))
+	if err := database.Connect(); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	if err := database.Migrate(); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	t.Cleanup(func() {
+		if db, err := database.DB.DB(); err == nil {
+			db.Close()
+		}
+	})
+	return repository.NewGorm(database.DB)
+}
+
+// seedSession is a session written by seedLeaderboard; change, if set,
+// adjusts the ended default-settings session built from the other fields
+type seedSession struct {
+	user        int // index into the seeded users, or -1 for anonymous
+	anonymousID string
+	score       int
+	startedAt   time.Duration // after the seed's start time
+	change      func(*models.Session)
+}
+
+// ineligible takes a session off the leaderboard
+func ineligible(s *models.Session) { s.LeaderboardEligible = false }
+
+// lasting sets a session's length in seconds
+func lasting(seconds int) func(*models.Session) {
+	return func(s *models.Session) { s.Duration = seconds }
+}
+
+// sprint makes a session a sprint of 20 problems, scored in milliseconds
+func sprint(milliseconds int) func(*models.Session) {
+	return func(s *models.Session) {
+		s.Mode = models.ModeSprint
+		s.TargetCount = 20
+		s.Score = milliseconds
+	}
+}
+
+// unfinished leaves a sprint open
+func unfinished(s *models.Session) {
+	sprint(1000)(s)
+	s.EndedAt = nil
+}
+
+// playedWith records that a session was played with non-default settings
+func playedWith(fingerprint string) func(*models.Session) {
+	return func(s *models.Session) {
+		s.IsDefaultSettings = false
+		s.SettingsFingerprint = fingerprint
+	}
+}
+
+// seedStart is when seeded sessions start, give or take their offset
+var seedStart = time.Date(2024, 1, 1, 12, 0, 0, 0, time.Local)
+
+// leaderboardSeed is written to every implementation under test
+var leaderboardSeed = []seedSession{
+	{0, "", 30, 0, nil},
+	{1, "", 40, time.Hour, nil},
+	{-1, "anon-1", 25, -time.Hour, nil},
+	{0, "", 35, 2 * time.Hour, nil},
+	{-1, "anon-1", 20, -2 * time.Hour, nil},
+	{-1, "", 15, 0, nil}, // predates anonymous identities
+	{2, "", 10, 0, nil},
+	{1, "", 99, 0, ineligible},
+	{2, "", 30, 0, nil},                 // ties with alice's first session, so ranked by ID
+	{-1, "anon-2", 30, -time.Hour, nil}, // ties too, but played first
+	{2, "", 50, 0, playedWith("add:1-9:1-9")},
+	{1, "", 20, 0, playedWith("add:1-9:1-9")},
+	{0, "", 12, 0, playedWith("mul:2-12:2-12")},
+	{0, "", 18, time.Hour, playedWith("add:1-9:1-9")},
+	{1, "", 60, 0, lasting(60)},
+	{2, "", 45, time.Hour, lasting(60)},
+	{0, "", 0, 0, sprint(45000)},
+	{1, "", 0, time.Hour, sprint(30000)},
+	{2, "", 0, 0, sprint(30000)}, // ties with bob but played first
+	{-1, "anon-3", 0, 0, unfinished},
+	{-1, "", 14, time.Hour, nil}, // also predates anonymous identities, so another player
+}
+
+// seedLeaderboard writes the same users and sessions to a set of
+// repositories, so IDs line up between implementations
+func seedLeaderboard(t *testing.T, repos repository.Repositories, seed []seedSession) {
+	var userIDs []uint
+	for _, name := range []string{"alice", "bob", "carol"} {
+		user := models.User{Username: name, Email: name + "@example.com"}
+		if err := repos.Users.Create(&user); err != nil {
+			t.Fatalf("create user: %v", err)
+		}
+		userIDs = append(userIDs, user.ID)
+	}
+
+	for _, s := range seed {
+		startedAt := seedStart.Add(s.startedAt)
+		ended := startedAt.Add(2 * time.Minute)
+		session := models.Session{
+			AnonymousID:         s.anonymousID,
+			Score:               s.score,
+			Duration:            120,
+			Mode:                models.ModeStandard,
+			IsDefaultSettings:   true,
+			LeaderboardEligible: true,
+			StartedAt:           startedAt,
+		}
+		if s.user >= 0 {
+			session.UserID = &userIDs[s.user]
+		}
+		if err := repos.Sessions.Create(&session); err != nil {
+			t.Fatalf("create session: %v", err)
+		}
+
+		// Sessions are scored and flagged when they end, as in the handlers
+		session.EndedAt = &ended
+		if s.change != nil {
+			s.change(&session)
+		}
+		if err := repos.Sessions.Update(&session); err != nil {
+			t.Fatalf("update session: %v", err)
+		}
+	}
+}
+
+// sessionIDs returns the IDs of sessions in order
+func sessionIDs(sessions []models.Session) []uint {
+	ids := make([]uint, len(sessions))
+	for i, session := range sessions {
+		ids[i] = session.ID
+	}
+	return ids
+}
+
+// standingSummary is the part of a player standing both implementations
+// must agree on
+type standingSummary struct {
+	ID       uint
+	Runs     int
+	Username string
+}
+
+func summarize(standings []repository.PlayerStanding) []standingSummary {
+	summaries := make([]standingSummary, len(standings))
+	for i, standing := range standings {
+		summaries[i] = standingSummary{ID: standing.Best.ID, Runs: standing.Runs}
+		if standing.Best.User != nil {
+			summaries[i].Username = standing.Best.User.Username
+		}
+	}
+	return summaries
+}
+
+// TestLeaderboardParity checks that the in-memory repositories rank
+// sessions exactly as the database does
+func TestLeaderboardParity(t *testing.T) {
+	memory := repository.NewMemory()
+	gorm := newGormRepositories(t)
+	seedLeaderboard(t, memory, leaderboardSeed)
+	seedLeaderboard(t, gorm, leaderboardSeed)
+
+	queries := map[string]repository.LeaderboardQuery{
+		"default":           {Limit: 10},
+		"first page":        {Limit: 2},
+		"second page":       {Limit: 2, Offset: 2},
+		"past the end":      {Limit: 10, Offset: 20},
+		"since":             {Limit: 10, Since: &seedStart},
+		"fingerprint":       {Limit: 10, Fingerprint: "add:1-9:1-9"},
+		"two minutes":       {Limit: 10, Duration: 120, Mode: models.ModeStandard},
+		"one minute":        {Limit: 10, Duration: 60},
+		"no sessions":       {Limit: 10, Duration: 300},
+		"standard":          {Limit: 10, Mode: models.ModeStandard},
+		"sprint":            {Limit: 10, Mode: models.ModeSprint, TargetCount: 20},
+		"sprint page":       {Limit: 1, Offset: 1, Mode: models.ModeSprint},
+		"other sprint size": {Limit: 10, Mode: models.ModeSprint, TargetCount: 50},
+	}
+
+	// Pin the expected order once, so agreeing on a wrong order still fails:
+	// best score first, then earliest start, then lowest ID
+	ranked, err := gorm.Sessions.Leaderboard(queries["two minutes"])
+	if err != nil {
+		t.Fatalf("gorm leaderboard: %v", err)
+	}
+	if want := []uint{2, 4, 10, 1, 9, 3, 5, 6, 21, 7}; !reflect.DeepEqual(sessionIDs(ranked), want) {
+		t.Fatalf("leaderboard = %v, want %v", sessionIDs(ranked), want)
+	}
+	ranked, err = gorm.Sessions.Leaderboard(queries["fingerprint"])
+	if err != nil {
+		t.Fatalf("gorm leaderboard: %v", err)
+	}
+	if want := []uint{11, 12, 14}; !reflect.DeepEqual(sessionIDs(ranked), want) {
+		t.Fatalf("fingerprint leaderboard = %v, want %v", sessionIDs(ranked), want)
+	}
+	ranked, err = gorm.Sessions.Leaderboard(queries["one minute"])
+	if err != nil {
+		t.Fatalf("gorm leaderboard: %v", err)
+	}
+	if want := []uint{15, 16}; !reflect.DeepEqual(sessionIDs(ranked), want) {
+		t.Fatalf("one minute leaderboard = %v, want %v", sessionIDs(ranked), want)
+	}
+	ranked, err = gorm.Sessions.Leaderboard(queries["sprint"])
+	if err != nil {
+		t.Fatalf("gorm leaderboard: %v", err)
+	}
+	if want := []uint{19, 18, 17}; !reflect.DeepEqual(sessionIDs(ranked), want) {
+		t.Fatalf("sprint leaderboard = %v, want %v", sessionIDs(ranked), want)
+	}
+
+	for name, query := range queries {
+		t.Run(name, func(t *testing.T) {
+			want, err := gorm.Sessions.Leaderboard(query)
+			if err != nil {
+				t.Fatalf("gorm leaderboard: %v", err)
+			}
+			got, err := memory.Sessions.Leaderboard(query)
+			if err != nil {
+				t.Fatalf("memory leaderboard: %v", err)
+			}
+			if !reflect.DeepEqual(sessionIDs(got), sessionIDs(want)) {
+				t.Errorf("leaderboard = %v, want %v", sessionIDs(got), sessionIDs(want))
+			}
+
+			wantPlayers, err := gorm.Sessions.PlayerLeaderboard(query)
+			if err != nil {
+				t.Fatalf("gorm players: %v", err)
+			}
+			gotPlayers, err := memory.Sessions.PlayerLeaderboard(query)
+			if err != nil {
+				t.Fatalf("memory players: %v", err)
+			}
+			if !reflect.DeepEqual(summarize(gotPlayers), summarize(wantPlayers)) {
+				t.Errorf("players = %+v, want %+v", summarize(gotPlayers), summarize(wantPlayers))
+			}
+
+			for userID := uint(1); userID <= 4; userID++ {
+				wantPos, wantTotal, wantErr := gorm.Sessions.PlayerPosition(query, userID)
+				gotPos, gotTotal, gotErr := memory.Sessions.PlayerPosition(query, userID)
+				if errors.Is(wantErr, repository.ErrNotFound) != errors.Is(gotErr, repository.ErrNotFound) ||
+					gotPos != wantPos || gotTotal != wantTotal {
+					t.Errorf("user %d position = %d of %d (%v), want %d of %d (%v)",
+						userID, gotPos, gotTotal, gotErr, wantPos, wantTotal, wantErr)
+				}
+			}
+
+			wantConfigs, err := gorm.Sessions.PopularConfigurations(query)
+			if err != nil {
+				t.Fatalf("gorm configurations: %v", err)
+			}
+			gotConfigs, err := memory.Sessions.PopularConfigurations(query)
+			if err != nil {
+				t.Fatalf("memory configurations: %v", err)
+			}
+			if (len(gotConfigs) > 0 || len(wantConfigs) > 0) && !reflect.DeepEqual(gotConfigs, wantConfigs) {
+				t.Errorf("configurations = %+v, want %+v", gotConfigs, wantConfigs)
+			}
+		})
+	}
+}
+
+// TestSettingsParity checks that settings read back as they were written,
+// including disabled operations and zero minimums
+func TestSettingsParity(t *testing.T) {
+	for name, repos := range map[string]repository.Repositories{
+		"memory": repository.NewMemory(),
+		"gorm":   newGormRepositories(t),
+	} {
+		t.Run(name, func(t *testing.T) {
+			for _, username := range []string{"alice", "bob"} {
+				user := models.User{Username: username, Email: username + "@example.com"}
+				if err := repos.Users.Create(&user); err != nil {
+					t.Fatalf("create user: %v", err)
+				}
+
+				want := models.Settings{
+					UserID: user.ID,
+					OperationSettings: models.OperationSettings{
+						MultiplicationEnabled: true,
+						MultiplicationMin1:    0,
+						MultiplicationMax1:    12,
+						MultiplicationMin2:    2,
+						MultiplicationMax2:    12,
+					},
+				}
+				settings := want
+				if err := repos.Settings.Create(&settings); err != nil {
+					t.Fatalf("create settings: %v", err)
+				}
+
+				got, err := repos.Settings.FindByUserID(user.ID)
+				if err != nil {
+					t.Fatalf("find settings: %v", err)
+				}
+				got.ID, got.CreatedAt, got.UpdatedAt, got.DeletedAt = 0, time.Time{}, time.Time{}, gorm.DeletedAt{}
+				if !reflect.DeepEqual(*got, want) {
+					t.Errorf("%s's settings = %+v, want %+v", username, *got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/calebwoo/mental-math-trainer/internal/models"
+)
+
+// ErrNotFound is returned when a requested record does not exist
+var ErrNotFound = errors.New("record not found")
+
+// UserRepository stores user accounts
+type UserRepository interface {
+	Create(user *models.User) error
+	FindByID(id uint) (*models.User, error)
+	FindByUsername(username string) (*models.User, error)
+	ExistsByUsernameOrEmail(username, email string) (bool, error)
+}
+
+// SessionRepository stores practice sessions
+type SessionRepository interface {
+	// Create also stores the session's Settings snapshot, if set
+	Create(session *models.Session) error
+	FindByID(id uint) (*models.Session, error)
+	// Update never changes the Settings snapshot
+	Update(session *models.Session) error
+	Delete(id uint) error
+
+	// FindSettings returns the settings snapshot taken when the session was
+	// created, or ErrNotFound if it has none
+	FindSettings(sessionID uint) (*models.SessionSettings, error)
+
+	// ListByUser and ListByAnonymousID return ended sessions newest first;
+	// sessions still in progress are left out
+	ListByUser(userID uint, limit, offset int) ([]models.Session, error)
+	ListByAnonymousID(anonymousID string, limit, offset int) ([]models.Session, error)
+
+	// ListOpen returns sessions that haven't ended and were started before
+	// the given time, oldest first
+	ListOpen(startedBefore time.Time) ([]models.Session, error)
+
+	// ClaimAnonymous moves every anonymous session of anonymousID onto the user
+	// and returns the number of sessions moved
+	ClaimAnonymous(anonymousID string, userID uint) (int, error)
+
+	// Leaderboard returns the top ranked sessions with their users loaded
+	Leaderboard(query LeaderboardQuery) ([]models.Session, error)
+
+	// PlayerLeaderboard ranks each player by their best session only
+	PlayerLeaderboard(query LeaderboardQuery) ([]PlayerStanding, error)
+
+	// PlayerPosition returns the zero-based position of a user on the players
+	// leaderboard and the number of ranked players, or ErrNotFound if the
+	// user has no ranked sessions. Limit and Offset are ignored.
+	PlayerPosition(query LeaderboardQuery, userID uint) (position, total int, err error)
+
+	// PopularConfigurations counts ranked sessions and players per settings
+	// fingerprint, most played first. The query's Fingerprint is ignored.
+	PopularConfigurations(query LeaderboardQuery) ([]ConfigurationStats, error)
+}
+
+// LeaderboardQuery selects the sessions shown on a leaderboard
+type LeaderboardQuery struct {
+	Limit       int
+	Offset      int
+	Since       *time.Time // only sessions started at or after Since; nil for all time
+	Fingerprint string     // only sessions with these settings; empty for default settings
+	Duration    int        // only sessions of this length in seconds; 0 for any length
+	Mode        string     // only sessions of this mode; empty for any mode
+	TargetCount int        // only sprints of this many problems; 0 for any count
+}
+
+// lowerIsBetter reports whether the query ranks sessions by ascending score,
+// as sprints are scored by elapsed time
+func (q LeaderboardQuery) lowerIsBetter() bool {
+	return q.Mode == models.ModeSprint
+}
+
+// ProblemFilter selects a user's problems by when their session started and
+// which session they belong to
+type ProblemFilter struct {
+	UserID     uint
+	From       *time.Time // sessions started at or after From; nil for no lower bound
+	To         *time.Time // sessions started before To; nil for no upper bound
+	SessionIDs []uint     // only these sessions; empty for all
+	Operation  string     // only problems of this operation; empty for all
+}
+
+// ConfigurationStats is how often a settings configuration has been played
+type ConfigurationStats struct {
+	Fingerprint string
+	Sessions    int
+	Players     int
+}
+
+// PlayerStanding is a player's best session on a leaderboard, with their user
+// loaded, and how many ranked sessions they played
+type PlayerStanding struct {
+	Best models.Session
+	Runs int
+}
+
+// ProblemRepository stores the problems of a session
+type ProblemRepository interface {
+	Create(problem *models.Problem) error
+	Update(problem *models.Problem) error
+	FindInSession(sessionID, problemID uint) (*models.Problem, error)
+
+	// FindPending returns the oldest unanswered problem of a session
+	FindPending(sessionID uint) (*models.Problem, error)
+
+	// ListAnswered returns the answered problems of a session in order
+	ListAnswered(sessionID uint) ([]models.Problem, error)
+
+	// ListAnsweredByUser returns the answered problems across a user's
+	// sessions, in order, narrowed by the filter
+	ListAnsweredByUser(filter ProblemFilter) ([]models.Problem, error)
+
+	CountCorrect(sessionID uint) (int, error)
+	DeleteBySession(sessionID uint) error
+}
+
+// SettingsRepository stores per-user problem settings
+type SettingsRepository interface {
+	FindByUserID(userID uint) (*models.Settings, error)
+	Create(settings *models.Settings) error
+	Update(settings *models.Settings) error
+}
+
+// FactRepository stores users' spaced-repetition schedules
+type FactRepository interface {
+	// Find returns a user's schedule for one fact
+	Find(userID uint, operation string, a, b int) (*models.FactSchedule, error)
+
+	// ListByUser returns a user's schedules for the given operations
+	ListByUser(userID uint, operations []string) ([]models.FactSchedule, error)
+
+	// Save creates the schedule if it has no ID, or updates it otherwise
+	Save(schedule *models.FactSchedule) error
+}
+
+// Repositories groups the repositories the API depends on
+type Repositories struct {
+	Users    UserRepository
+	Sessions SessionRepository
+	Problems ProblemRepository
+	Settings SettingsRepository
+	Facts    FactRepository
+}
@@ -0,0 +1,134 @@
+// Package sweeper ends sessions that were started but never completed
+package sweeper
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/calebwoo/mental-math-trainer/internal/models"
+	"github.com/calebwoo/mental-math-trainer/internal/repository"
+)
+
+// Policy decides when an open session counts as abandoned and what happens
+// to it
+type Policy struct {
+	Grace        time.Duration // Time after a session's deadline before it is abandoned, as for the handlers
+	AbandonAfter time.Duration // Time after starting before a session with no deadline, such as a sprint, is abandoned
+	DiscardEmpty bool          // Delete abandoned sessions with no answered problems instead of ending them
+}
+
+// Sweeper finds abandoned sessions and finalizes or discards them
+type Sweeper struct {
+	sessions repository.SessionRepository
+	problems repository.ProblemRepository
+	policy   Policy
+}
+
+// New creates a sweeper backed by the given repositories
+func New(repos repository.Repositories, policy Policy) *Sweeper {
+	return &Sweeper{
+		sessions: repos.Sessions,
+		problems: repos.Problems,
+		policy:   policy,
+	}
+}
+
+// Run sweeps every interval until the context is cancelled
+func (s *Sweeper) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			finalized, discarded, err := s.Sweep(now)
+			if err != nil {
+				log.Printf("Failed to sweep sessions: %v", err)
+			}
+			if finalized > 0 || discarded > 0 {
+				log.Printf("Swept abandoned sessions: %d finalized, %d discarded", finalized, discarded)
+			}
+		}
+	}
+}
+
+// Sweep finalizes or discards every session abandoned as of now and returns
+// how many of each there were. Timed sessions are ended at their deadline
+// and scored by the correct problems recorded in time; sprints are ended
+// unranked, like sprints given up early.
+func (s *Sweeper) Sweep(now time.Time) (finalized, discarded int, err error) {
+	open, err := s.sessions.ListOpen(now.Add(-min(s.policy.Grace, s.policy.AbandonAfter)))
+	if err != nil {
+		return 0, 0, err
+	}
+
+	for i := range open {
+		session := &open[i]
+		endedAt, abandoned := s.abandonedAt(session, now)
+		if !abandoned {
+			continue
+		}
+
+		answered, err := s.problems.ListAnswered(session.ID)
+		if err != nil {
+			return finalized, discarded, err
+		}
+
+		if len(answered) == 0 && s.policy.DiscardEmpty {
+			if err := s.discard(session); err != nil {
+				return finalized, discarded, err
+			}
+			discarded++
+			continue
+		}
+
+		if err := s.finalize(session, answered, endedAt); err != nil {
+			return finalized, discarded, err
+		}
+		finalized++
+	}
+	return finalized, discarded, nil
+}
+
+// abandonedAt reports whether an open session is abandoned as of now, and
+// when it should be recorded as ending
+func (s *Sweeper) abandonedAt(session *models.Session, now time.Time) (time.Time, bool) {
+	if deadline, limited := session.Deadline(); limited {
+		return deadline, now.After(deadline.Add(s.policy.Grace))
+	}
+	return now, now.After(session.StartedAt.Add(s.policy.AbandonAfter))
+}
+
+// finalize ends an abandoned session, recording why
+func (s *Sweeper) finalize(session *models.Session, answered []models.Problem, endedAt time.Time) error {
+	correct := 0
+	for _, problem := range answered {
+		if problem.IsCorrect {
+			correct++
+		}
+	}
+
+	session.EndedAt = &endedAt
+	if session.Mode == models.ModeSprint {
+		session.Score = 0
+		session.LeaderboardEligible = false
+		session.FlagReason = fmt.Sprintf("sprint ended after %d of %d correct problems", correct, session.TargetCount)
+		session.AbandonReason = fmt.Sprintf("sprint not finished within %s", s.policy.AbandonAfter)
+	} else {
+		session.Score = correct
+		session.AbandonReason = "time ran out without the session being completed"
+	}
+	return s.sessions.Update(session)
+}
+
+// discard deletes an abandoned session along with any unanswered problems
+func (s *Sweeper) discard(session *models.Session) error {
+	if err := s.problems.DeleteBySession(session.ID); err != nil {
+		return err
+	}
+	return s.sessions.Delete(session.ID)
+}
@@ -0,0 +1,178 @@
+package sweeper
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/calebwoo/mental-math-trainer/internal/models"
+	"github.com/calebwoo/mental-math-trainer/internal/repository"
+)
+
+var policy = Policy{
+	Grace:        10 * time.Second,
+	AbandonAfter: 30 * time.Minute,
+	DiscardEmpty: true,
+}
+
+// sweepTest seeds sessions into in-memory repositories
+type sweepTest struct {
+	t     *testing.T
+	repos repository.Repositories
+	now   time.Time
+}
+
+func newSweepTest(t *testing.T) *sweepTest {
+	return &sweepTest{t: t, repos: repository.NewMemory(), now: time.Now()}
+}
+
+// session stores an open session of the given mode started ago before now,
+// with one problem per answer; a nil answer is issued but not answered
+func (st *sweepTest) session(mode string, ago time.Duration, answers ...*bool) models.Session {
+	session := models.Session{
+		Duration:            120,
+		Mode:                mode,
+		LeaderboardEligible: true,
+		StartedAt:           st.now.Add(-ago),
+	}
+	if mode == models.ModeSprint {
+		session.TargetCount = 20
+	}
+	if err := st.repos.Sessions.Create(&session); err != nil {
+		st.t.Fatalf("create session: %v", err)
+	}
+
+	for _, correct := range answers {
+		problem := models.Problem{SessionID: session.ID, Question: "3 × 4", Answer: 12}
+		if correct != nil {
+			userAnswer := 12
+			if !*correct {
+				userAnswer = 13
+			}
+			problem.UserAnswer = &userAnswer
+			problem.IsCorrect = *correct
+		}
+		if err := st.repos.Problems.Create(&problem); err != nil {
+			st.t.Fatalf("create problem: %v", err)
+		}
+	}
+	return session
+}
+
+func (st *sweepTest) find(id uint) (*models.Session, error) {
+	return st.repos.Sessions.FindByID(id)
+}
+
+func answered(correct bool) *bool { return &correct }
+
+func TestSweep(t *testing.T) {
+	st := newSweepTest(t)
+	timed := st.session(models.ModeStandard, 3*time.Minute, answered(true), answered(false), answered(true))
+	empty := st.session(models.ModeStandard, 3*time.Minute)
+	unanswered := st.session(models.ModeStandard, 3*time.Minute, nil)
+	inGrace := st.session(models.ModeStandard, 125*time.Second)
+	playing := st.session(models.ModeStandard, time.Minute, answered(true))
+	sprint := st.session(models.ModeSprint, time.Hour, answered(true))
+	recentSprint := st.session(models.ModeSprint, 10*time.Minute, answered(true))
+
+	finalized, discarded, err := New(st.repos, policy).Sweep(st.now)
+	if err != nil {
+		t.Fatalf("sweep: %v", err)
+	}
+	if finalized != 2 || discarded != 2 {
+		t.Errorf("sweep finalized %d and discarded %d, want 2 and 2", finalized, discarded)
+	}
+
+	// Timed sessions end at their deadline, scored by their correct problems
+	session, err := st.find(timed.ID)
+	if err != nil {
+		t.Fatalf("find timed session: %v", err)
+	}
+	deadline := timed.StartedAt.Add(120 * time.Second)
+	if session.EndedAt == nil || !session.EndedAt.Equal(deadline) || session.Score != 2 || session.AbandonReason == "" {
+		t.Errorf("timed session ended at %v with score %d and reason %q, want %v, 2 and a reason",
+			session.EndedAt, session.Score, session.AbandonReason, deadline)
+	}
+	if !session.LeaderboardEligible {
+		t.Errorf("timed session was taken off the leaderboard")
+	}
+
+	// Sprints end unranked
+	session, err = st.find(sprint.ID)
+	if err != nil {
+		t.Fatalf("find sprint: %v", err)
+	}
+	if session.EndedAt == nil || session.Score != 0 || session.LeaderboardEligible || session.AbandonReason == "" {
+		t.Errorf("sprint ended at %v with score %d, eligible %v and reason %q; want ended, 0, false and a reason",
+			session.EndedAt, session.Score, session.LeaderboardEligible, session.AbandonReason)
+	}
+
+	// Sessions without answered problems are deleted, along with any problems
+	for _, id := range []uint{empty.ID, unanswered.ID} {
+		if _, err := st.find(id); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("session %d: err = %v, want it deleted", id, err)
+		}
+	}
+	if _, err := st.repos.Problems.FindPending(unanswered.ID); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("unanswered problem: err = %v, want it deleted", err)
+	}
+
+	// Sessions still in their time, grace period or sprint allowance stay open
+	for _, id := range []uint{inGrace.ID, playing.ID, recentSprint.ID} {
+		session, err := st.find(id)
+		if err != nil {
+			t.Fatalf("find session %d: %v", id, err)
+		}
+		if session.EndedAt != nil {
+			t.Errorf("session %d was ended at %v", id, session.EndedAt)
+		}
+	}
+}
+
+func TestSweepKeepsEmptySessions(t *testing.T) {
+	st := newSweepTest(t)
+	empty := st.session(models.ModeStandard, 3*time.Minute)
+
+	keep := policy
+	keep.DiscardEmpty = false
+	finalized, discarded, err := New(st.repos, keep).Sweep(st.now)
+	if err != nil {
+		t.Fatalf("sweep: %v", err)
+	}
+	if finalized != 1 || discarded != 0 {
+		t.Errorf("sweep finalized %d and discarded %d, want 1 and 0", finalized, discarded)
+	}
+
+	session, err := st.find(empty.ID)
+	if err != nil {
+		t.Fatalf("find session: %v", err)
+	}
+	if session.EndedAt == nil || session.Score != 0 {
+		t.Errorf("empty session ended at %v with score %d, want ended with 0", session.EndedAt, session.Score)
+	}
+}
+
+func TestSweepEndsPenaltySessionsAtTheirShortenedDeadline(t *testing.T) {
+	st := newSweepTest(t)
+	penalty := st.session(models.ModePenalty, 2*time.Minute, answered(false), answered(true))
+	stored, err := st.find(penalty.ID)
+	if err != nil {
+		t.Fatalf("find session: %v", err)
+	}
+	stored.PenaltySeconds = 30
+	if err := st.repos.Sessions.Update(stored); err != nil {
+		t.Fatalf("update session: %v", err)
+	}
+
+	if _, _, err := New(st.repos, policy).Sweep(st.now); err != nil {
+		t.Fatalf("sweep: %v", err)
+	}
+	session, err := st.find(penalty.ID)
+	if err != nil {
+		t.Fatalf("find session: %v", err)
+	}
+	deadline := penalty.StartedAt.Add(90 * time.Second)
+	if session.EndedAt == nil || !session.EndedAt.Equal(deadline) || session.Score != 1 {
+		t.Errorf("penalty session ended at %v with score %d, want %v and 1", session.EndedAt, session.Score, deadline)
+	}
+}
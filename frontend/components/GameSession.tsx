@@ -33,14 +33,21 @@ export default function GameSession({ settings, onComplete }: GameSessionProps)
   const scoreRef = useRef(0);  // Use ref to avoid timer dependency issues
   const completedProblemsRef = useRef<LocalProblem[]>([]);  // Store problems locally
   const sessionStartTimeRef = useRef(Date.now());  // Track actual start time
+  const sessionRef = useRef<Promise<{ session_id: number }> | null>(null);  // Session created when play starts
+  const submissionsRef = useRef<Promise<unknown>[]>([]);  // Problems uploaded as they are answered
 
   // Keep scoreRef synchronized with score state
   useEffect(() => {
     scoreRef.current = score;
   }, [score]);
 
-  // Initialize first problem only (don't create session yet)
+  // Initialize first problem and start the session. The server enforces the
+  // session's time limit from when it was created, so create it as play starts.
   useEffect(() => {
+    if (!sessionRef.current) {
+      sessionRef.current = api.createSession(isUsingDefaultSettings(settings), settings);
+      sessionRef.current.catch(() => {});  // Reported when the session ends
+    }
     const problem = generatorRef.current.generateProblem();
     setCurrentProblem(problem);
     setProblemStartTime(Date.now());
@@ -71,18 +78,12 @@ export default function GameSession({ settings, onComplete }: GameSessionProps)
       setIsSessionActive(false); // Prevent double submission
       const endSession = async () => {
         try {
-          // Create session and submit all problems at once
-          const isDefault = isUsingDefaultSettings(settings);
-          console.log('Creating session with isDefaultSettings:', isDefault);
-          const response = await api.createSession(isDefault);
+          // Problems were submitted as they were answered, so only wait for
+          // the last uploads before completing
+          const response = await sessionRef.current!;
           const sessionId = response.session_id;
-          console.log('Session created with ID:', sessionId);
-
-          // Submit all completed problems
-          console.log(`Submitting ${completedProblemsRef.current.length} problems...`);
-          for (const problem of completedProblemsRef.current) {
-            await api.submitProblem(sessionId, problem);
-          }
+          console.log(`Waiting for ${submissionsRef.current.length} problem submissions...`);
+          await Promise.all(submissionsRef.current);
 
           // Complete the session
           console.log('Completing session with score:', scoreRef.current);
@@ -105,6 +106,20 @@ export default function GameSession({ settings, onComplete }: GameSessionProps)
     }
   }, [timeRemaining, isSessionActive, onComplete, settings]);
 
+  // Upload a problem as soon as it is answered, while the session's time
+  // lasts; uploads run in order so the session records problems as played
+  const submitProblem = (problem: LocalProblem) => {
+    const previous = submissionsRef.current[submissionsRef.current.length - 1] ?? Promise.resolve();
+    const submission = previous
+      .catch(() => {})
+      .then(async () => {
+        const { session_id } = await sessionRef.current!;
+        await api.submitProblem(session_id, problem);
+      });
+    submission.catch(() => {});  // Reported when the session ends
+    submissionsRef.current.push(submission);
+  };
+
   const handleInputChange = async (e: React.ChangeEvent<HTMLInputElement>) => {
     const newValue = e.target.value;
 
@@ -132,6 +147,7 @@ export default function GameSession({ settings, onComplete }: GameSessionProps)
           typo_count: typoCount,
         });
         console.log(`Problem answered correctly! Total problems: ${completedProblemsRef.current.length}`);
+        submitProblem(completedProblemsRef.current[completedProblemsRef.current.length - 1]);
 
         // Increment score and move to next problem
         setScore((prev) => prev + 1);
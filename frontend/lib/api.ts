@@ -22,15 +22,70 @@ const removeToken = () => {
   }
 };
 
+// Anonymous identity issued by the backend on the first anonymous session
+const getAnonymousToken = () => {
+  if (typeof window !== 'undefined') {
+    return localStorage.getItem('anonymousToken');
+  }
+  return null;
+};
+
+const setAnonymousToken = (token: string) => {
+  if (typeof window !== 'undefined') {
+    localStorage.setItem('anonymousToken', token);
+  }
+};
+
+// Anonymous session secrets, keyed by session ID
+const getSessionSecrets = (): Record<string, string> => {
+  if (typeof window !== 'undefined') {
+    try {
+      return JSON.parse(localStorage.getItem('sessionSecrets') || '{}');
+    } catch {
+      return {};
+    }
+  }
+  return {};
+};
+
+const setSessionSecret = (sessionId: number, secret: string) => {
+  if (typeof window !== 'undefined') {
+    const secrets = getSessionSecrets();
+    secrets[sessionId] = secret;
+    localStorage.setItem('sessionSecrets', JSON.stringify(secrets));
+  }
+};
+
+// Proof of anonymous play, so the backend can move those sessions onto the account
+const getAnonymousClaims = () => ({
+  anonymous_token: getAnonymousToken() || undefined,
+  session_claims: Object.entries(getSessionSecrets()).map(([sessionId, secret]) => ({
+    session_id: Number(sessionId),
+    session_secret: secret,
+  })),
+});
+
 // Helper to add auth header to requests
-const getHeaders = (includeContentType = true) => {
-  const headers: HeadersInit = {};
+const getHeaders = (includeContentType = true, sessionId?: number) => {
+  const headers: Record<string, string> = {};
   const token = getToken();
 
   if (token) {
     headers['Authorization'] = `Bearer ${token}`;
   }
 
+  const anonymousToken = getAnonymousToken();
+  if (anonymousToken) {
+    headers['X-Anonymous-Token'] = anonymousToken;
+  }
+
+  if (sessionId !== undefined) {
+    const secret = getSessionSecrets()[sessionId];
+    if (secret) {
+      headers['X-Session-Secret'] = secret;
+    }
+  }
+
   if (includeContentType) {
     headers['Content-Type'] = 'application/json';
   }
@@ -44,7 +99,7 @@ export const api = {
     const response = await fetch(`${API_URL}/auth/register`, {
       method: 'POST',
       headers: { 'Content-Type': 'application/json' },
-      body: JSON.stringify({ username, email, password }),
+      body: JSON.stringify({ username, email, password, ...getAnonymousClaims() }),
     });
     if (!response.ok) {
       const error = await response.json().catch(() => ({ error: 'Registration failed' }));
@@ -59,7 +114,7 @@ export const api = {
     const response = await fetch(`${API_URL}/auth/login`, {
       method: 'POST',
       headers: { 'Content-Type': 'application/json' },
-      body: JSON.stringify({ username, password }),
+      body: JSON.stringify({ username, password, ...getAnonymousClaims() }),
     });
     if (!response.ok) {
       const error = await response.json().catch(() => ({ error: 'Login failed' }));
@@ -90,19 +145,26 @@ export const api = {
   },
 
   // Session endpoints
-  async createSession(isDefaultSettings = false): Promise<{ session_id: number; started_at: string }> {
+  async createSession(isDefaultSettings = false, settings?: Settings): Promise<{ session_id: number; started_at: string; session_secret?: string; anonymous_token?: string }> {
     const response = await fetch(`${API_URL}/sessions`, {
       method: 'POST',
       headers: getHeaders(),
-      body: JSON.stringify({ is_default_settings: isDefaultSettings }),
+      body: JSON.stringify({ is_default_settings: isDefaultSettings, settings }),
     });
     if (!response.ok) throw new Error('Failed to create session');
-    return response.json();
+    const data = await response.json();
+    if (data.session_secret) {
+      setSessionSecret(data.session_id, data.session_secret);
+    }
+    if (data.anonymous_token) {
+      setAnonymousToken(data.anonymous_token);
+    }
+    return data;
   },
 
   async getSession(sessionId: number): Promise<Session> {
     const response = await fetch(`${API_URL}/sessions/${sessionId}`, {
-      headers: getHeaders(false),
+      headers: getHeaders(false, sessionId),
     });
     if (!response.ok) throw new Error('Failed to fetch session');
     return response.json();
@@ -111,7 +173,7 @@ export const api = {
   async completeSession(sessionId: number, score: number): Promise<Session> {
     const response = await fetch(`${API_URL}/sessions/${sessionId}/complete`, {
       method: 'PATCH',
-      headers: getHeaders(),
+      headers: getHeaders(true, sessionId),
       body: JSON.stringify({ score }),
     });
     if (!response.ok) throw new Error('Failed to complete session');
@@ -134,7 +196,7 @@ export const api = {
   async deleteSession(sessionId: number): Promise<void> {
     const response = await fetch(`${API_URL}/sessions/${sessionId}`, {
       method: 'DELETE',
-      headers: getHeaders(false),
+      headers: getHeaders(false, sessionId),
     });
     if (!response.ok) throw new Error('Failed to delete session');
   },
@@ -152,7 +214,7 @@ export const api = {
   ): Promise<Problem> {
     const response = await fetch(`${API_URL}/sessions/${sessionId}/problems`, {
       method: 'POST',
-      headers: getHeaders(),
+      headers: getHeaders(true, sessionId),
       body: JSON.stringify(problem),
     });
     if (!response.ok) throw new Error('Failed to submit problem');
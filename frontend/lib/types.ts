@@ -71,6 +71,7 @@ export interface User {
 export interface AuthResponse {
   token: string;
   user: User;
+  claimed_sessions?: number;
 }
 
 export interface LeaderboardEntry {